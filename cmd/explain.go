@@ -0,0 +1,173 @@
+//
+// Copyright 2019 Chef Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chef/chef-analyze/pkg/reporting"
+)
+
+var (
+	reportExplainCmd = &cobra.Command{
+		Use:   "explain <name>[@<version>]",
+		Short: "Deep-dive into a single cookbook's cookstyle violations",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return explain.run(args[0])
+		},
+	}
+	// explain holds every flag bound to `report cookbooks explain`.
+	explain = &cliExplain{}
+)
+
+// cliExplain is the flag-bound state and behavior of
+// `report cookbooks explain`. It reuses newChefClient() (cmd/report.go) so
+// credential/reporting setup isn't duplicated between the two commands.
+type cliExplain struct {
+	onlyCorrectable bool
+	cop             string
+	json            bool
+}
+
+func init() {
+	reportExplainCmd.Flags().BoolVar(
+		&explain.onlyCorrectable,
+		"only-correctable", false,
+		"only show offenses cookstyle can auto-correct",
+	)
+	reportExplainCmd.Flags().StringVar(
+		&explain.cop,
+		"cop", "",
+		"only show offenses reported by this cop",
+	)
+	reportExplainCmd.Flags().BoolVar(
+		&explain.json,
+		"json", false,
+		"output the explanation as JSON instead of human readable text",
+	)
+	// adds the explain command as a sub-command of 'report cookbooks'
+	// => chef-analyze report cookbooks explain <name>[@<version>]
+	reportCookbooksCmd.AddCommand(reportExplainCmd)
+}
+
+func (c *cliExplain) run(arg string) error {
+	name, version := parseCookbookArg(arg)
+
+	chefClient, _, err := newChefClient()
+	if err != nil {
+		return err
+	}
+
+	explanation, err := reporting.Explain(chefClient, name, version)
+	if err != nil {
+		return err
+	}
+
+	if explanation.DownloadError != nil {
+		return fmt.Errorf("could not download cookbook %s: %w", name, explanation.DownloadError)
+	}
+	if explanation.CookstyleError != nil {
+		return fmt.Errorf("could not run cookstyle against cookbook %s: %w", name, explanation.CookstyleError)
+	}
+
+	explanation.Offenses = c.filterOffenses(explanation.Offenses)
+
+	if c.json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(explanation)
+	}
+
+	writeExplanation(explanation)
+	return nil
+}
+
+// filterOffenses applies --only-correctable and --cop to the offenses an
+// explanation would otherwise print.
+func (c *cliExplain) filterOffenses(offenses []reporting.OffenseExplanation) []reporting.OffenseExplanation {
+	filtered := make([]reporting.OffenseExplanation, 0, len(offenses))
+	for _, o := range offenses {
+		if c.onlyCorrectable && !o.Offense.Correctable {
+			continue
+		}
+		if c.cop != "" && o.Offense.CopName != c.cop {
+			continue
+		}
+		filtered = append(filtered, o)
+	}
+	return filtered
+}
+
+// parseCookbookArg splits a "name" or "name@version" CLI argument.
+func parseCookbookArg(arg string) (name, version string) {
+	parts := strings.SplitN(arg, "@", 2)
+	if len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	return parts[0], ""
+}
+
+func writeExplanation(e *reporting.CookbookExplanation) {
+	fmt.Printf("Cookbook: %s (%s)\n\n", e.Name, e.Version)
+
+	fmt.Println("Files and offenses:")
+	currentFile := ""
+	for _, o := range e.Offenses {
+		if o.File != currentFile {
+			fmt.Printf("\n%s:\n", o.File)
+			currentFile = o.File
+		}
+		fmt.Printf("  line %d: %s (auto-correctable: %t)\n", o.Offense.Location.Line, o.Offense.CopName, o.Offense.Correctable)
+		fmt.Printf("    %s\n", o.Offense.Message)
+		for i, line := range o.Snippet {
+			marker := "  "
+			if o.SnippetStartLine+i == o.Offense.Location.Line {
+				marker = "->"
+			}
+			fmt.Printf("    %s %4d | %s\n", marker, o.SnippetStartLine+i, line)
+		}
+	}
+
+	fmt.Println("\nCop rollup:")
+	for _, r := range e.CopRollup {
+		fmt.Printf("  %-40s %4d offenses, %.0f%% auto-correctable\n", r.CopName, r.Count, r.CorrectableRatio()*100)
+	}
+
+	fmt.Println("\nUsage:")
+	if e.UsageLookupError != nil {
+		fmt.Printf("  could not determine usage: %v\n", e.UsageLookupError)
+	} else {
+		fmt.Printf("  Nodes:        %s\n", joinOrNone(e.Nodes))
+		fmt.Printf("  Roles:        %s\n", joinOrNone(e.Roles))
+		fmt.Printf("  Environments: %s\n", joinOrNone(e.Environments))
+	}
+
+	fmt.Printf("\nSuggested fix: %s\n", e.SuggestedFix())
+}
+
+func joinOrNone(items []string) string {
+	if len(items) == 0 {
+		return "none"
+	}
+	return strings.Join(items, ", ")
+}
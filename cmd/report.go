@@ -19,8 +19,12 @@ package cmd
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"runtime"
+	"strconv"
 	"strings"
 
 	"golang.org/x/crypto/ssh/terminal"
@@ -46,42 +50,7 @@ var (
 		Short: "Generates a cookbook oriented report",
 		Args:  cobra.NoArgs,
 		RunE: func(_ *cobra.Command, _ []string) error {
-			creds, err := credentials.FromViper(
-				globalFlags.profile,
-				overrideCredentials(),
-			)
-
-			if err != nil {
-				return err
-			}
-
-			cfg := &reporting.Reporting{Credentials: creds}
-			if globalFlags.noSSLverify {
-				cfg.NoSSLVerify = true
-			}
-
-			chefClient, err := reporting.NewChefClient(cfg)
-			if err != nil {
-				return err
-			}
-
-			cookbooksState, err := reporting.NewCookbooks(chefClient.Cookbooks, chefClient.Search, cookbooksFlags.skipUnused)
-			if err != nil {
-				return err
-			}
-
-			if cookbooksFlags.detailed {
-				switch cookbooksFlags.format {
-				case "csv":
-					writeDetailedCSV(cookbooksState.Records)
-				default:
-					writeDetailedCookbookStateReport(cookbooksState.Records)
-				}
-				return nil
-			}
-
-			writeCookbookStateReport(cookbooksState.Records)
-			return nil
+			return cookbooks.run()
 		},
 	}
 	reportNodesCmd = &cobra.Command{
@@ -89,21 +58,7 @@ var (
 		Short: "Generates a nodes oriented report",
 		Args:  cobra.NoArgs,
 		RunE: func(_ *cobra.Command, _ []string) error {
-			creds, err := credentials.FromViper(
-				globalFlags.profile,
-				overrideCredentials(),
-			)
-
-			if err != nil {
-				return err
-			}
-
-			cfg := &reporting.Reporting{Credentials: creds}
-			if globalFlags.noSSLverify {
-				cfg.NoSSLVerify = true
-			}
-
-			chefClient, err := reporting.NewChefClient(cfg)
+			chefClient, cfg, err := newChefClient()
 			if err != nil {
 				return err
 			}
@@ -117,28 +72,175 @@ var (
 			return nil
 		},
 	}
-	cookbooksFlags struct {
-		detailed   bool
-		skipUnused bool
-		format     string
-	}
+	// cookbooks holds every flag bound to `report cookbooks` as well as
+	// the RunE logic itself, so that cliExplain (cmd/explain.go) can reuse
+	// newChefClient() without duplicating the credentials/client setup
+	// that used to live inline in this command's closure.
+	cookbooks = &cliCookbooks{}
 )
 
+// cliCookbooks is the flag-bound state and behavior of `report cookbooks`.
+type cliCookbooks struct {
+	detailed      bool
+	skipUnused    bool
+	format        string
+	fix           bool
+	freeze        bool
+	dryRun        bool
+	force         bool
+	outputFile    string
+	concurrency   int
+	noProgress    bool
+	scanSecrets   bool
+	secretsConfig string
+}
+
+// newChefClient builds an authenticated Chef Infra Server client from the
+// global `--profile`/`--config`/`--no-ssl-verify` flags. It is shared by
+// every report subcommand (cookbooks, nodes, explain) so credential
+// handling only lives in one place.
+func newChefClient() (*reporting.ChefClient, *reporting.Reporting, error) {
+	creds, err := credentials.FromViper(
+		globalFlags.profile,
+		overrideCredentials(),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg := &reporting.Reporting{Credentials: creds}
+	if globalFlags.noSSLverify {
+		cfg.NoSSLVerify = true
+	}
+
+	chefClient, err := reporting.NewChefClient(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return chefClient, cfg, nil
+}
+
+func (c *cliCookbooks) run() error {
+	chefClient, _, err := newChefClient()
+	if err != nil {
+		return err
+	}
+
+	isTTY := terminal.IsTerminal(int(os.Stdout.Fd()))
+	progress := reporting.NewProgress(os.Stdout, isTTY, c.noProgress)
+
+	cookbooksState, err := reporting.NewCookbooks(
+		chefClient.Cookbooks, chefClient.Search, c.skipUnused,
+		c.concurrency, progress,
+	)
+	progress.Done()
+	if err != nil {
+		return err
+	}
+
+	if c.fix {
+		fixResults := reporting.FixCookbooks(chefClient, cookbooksState.Records, reporting.FixOptions{
+			Freeze: c.freeze,
+			DryRun: c.dryRun,
+			Force:  c.force,
+		})
+		writeFixSummary(fixResults)
+	}
+
+	if c.scanSecrets {
+		if err := scanCookbooksForSecrets(cookbooksState.Records, c.secretsConfig); err != nil {
+			return err
+		}
+	}
+
+	out := os.Stdout
+	if c.outputFile != "" {
+		f, err := os.Create(c.outputFile)
+		if err != nil {
+			return fmt.Errorf("unable to open %q for writing: %w", c.outputFile, err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	switch c.format {
+	case "csv":
+		writeDetailedCSV(out, cookbooksState.Records)
+	case "json":
+		return writeCookbookStateReportJSON(out, cookbooksState.Records)
+	case "sarif":
+		return writeCookbookStateReportSARIF(out, cookbooksState.Records)
+	default:
+		if c.detailed {
+			writeDetailedCookbookStateReport(out, cookbooksState.Records)
+		} else {
+			writeCookbookStateReport(out, cookbooksState.Records)
+		}
+	}
+	return nil
+}
+
 func init() {
 	reportCookbooksCmd.PersistentFlags().BoolVarP(
-		&cookbooksFlags.detailed,
+		&cookbooks.detailed,
 		"detailed", "d", false,
 		"include detailed information about cookbook violations",
 	)
 	reportCookbooksCmd.PersistentFlags().BoolVarP(
-		&cookbooksFlags.skipUnused,
+		&cookbooks.skipUnused,
 		"skip-unused", "u", false,
 		"do not include unused cookbooks and versions that are not applied to any nodes",
 	)
 	reportCookbooksCmd.PersistentFlags().StringVarP(
-		&cookbooksFlags.format,
+		&cookbooks.format,
 		"format", "f", "txt",
-		"output format: txt is human readable, csv is machine readable",
+		"output format: txt is human readable, csv/json are machine readable, sarif is for CI code-scanning integrations",
+	)
+	reportCookbooksCmd.PersistentFlags().StringVarP(
+		&cookbooks.outputFile,
+		"output-file", "o", "",
+		"write the report to this file instead of stdout",
+	)
+	reportCookbooksCmd.PersistentFlags().BoolVar(
+		&cookbooks.fix,
+		"fix", false,
+		"automatically correct violations with cookstyle and upload the result back to the Chef Infra Server",
+	)
+	reportCookbooksCmd.PersistentFlags().BoolVar(
+		&cookbooks.freeze,
+		"freeze", false,
+		"freeze the cookbook version uploaded by --fix so it can't be overwritten",
+	)
+	reportCookbooksCmd.PersistentFlags().BoolVar(
+		&cookbooks.dryRun,
+		"dry-run", false,
+		"with --fix, run cookstyle but do not upload the corrected cookbook",
+	)
+	reportCookbooksCmd.PersistentFlags().BoolVar(
+		&cookbooks.force,
+		"force", false,
+		"with --fix, upload a cookbook even when cookstyle found nothing to auto-correct",
+	)
+	reportCookbooksCmd.PersistentFlags().IntVarP(
+		&cookbooks.concurrency,
+		"concurrency", "j", runtime.NumCPU(),
+		"number of cookbooks to fetch and analyze in parallel",
+	)
+	reportCookbooksCmd.PersistentFlags().BoolVar(
+		&cookbooks.noProgress,
+		"no-progress", false,
+		"disable the in-place progress UI and print a plain-text log instead",
+	)
+	reportCookbooksCmd.PersistentFlags().BoolVar(
+		&cookbooks.scanSecrets,
+		"scan-secrets", false,
+		"scan every downloaded cookbook for hardcoded credentials",
+	)
+	reportCookbooksCmd.PersistentFlags().StringVar(
+		&cookbooks.secretsConfig,
+		"secrets-config", "",
+		"path to a TOML or YAML file with custom secret rules and allowlists",
 	)
 	// adds the cookbooks command as a sub-command of the report command
 	// => chef-analyze report cookbooks
@@ -149,7 +251,7 @@ func init() {
 }
 
 // TODO different output depending on flags or TTY?
-func writeCookbookStateReport(records []*reporting.CookbookRecord) {
+func writeCookbookStateReport(out io.Writer, records []*reporting.CookbookRecord) {
 	var (
 		downloadErrors   strings.Builder
 		usageFetchErrors strings.Builder
@@ -159,7 +261,7 @@ func writeCookbookStateReport(records []*reporting.CookbookRecord) {
 		var strBuilder strings.Builder
 
 		// skip unused cookbooks
-		if len(record.Nodes) == 0 && cookbooksFlags.skipUnused {
+		if len(record.Nodes) == 0 && cookbooks.skipUnused {
 			continue
 		}
 
@@ -167,6 +269,9 @@ func writeCookbookStateReport(records []*reporting.CookbookRecord) {
 		strBuilder.WriteString(fmt.Sprintf("%v violations, %v auto-correctable, %v nodes affected",
 			record.NumOffenses(), record.NumCorrectable(), len(record.Nodes)),
 		)
+		if len(record.Secrets) > 0 {
+			strBuilder.WriteString(fmt.Sprintf(", %v secrets found", len(record.Secrets)))
+		}
 
 		if record.DownloadError != nil {
 			strBuilder.WriteString("\nERROR: could not download cookbook (see end of report)")
@@ -179,14 +284,13 @@ func writeCookbookStateReport(records []*reporting.CookbookRecord) {
 			usageFetchErrors.WriteString(fmt.Sprintf(" - %s (%s): %v\n", record.Name, record.Version, record.UsageLookupError))
 		}
 
-		// TODO @afiune write report to disk
-		fmt.Println(strBuilder.String())
+		fmt.Fprintln(out, strBuilder.String())
 	}
 
 	writeErrorBuilders(downloadErrors, cookstyleErrors, usageFetchErrors)
 }
 
-func writeDetailedCookbookStateReport(records []*reporting.CookbookRecord) {
+func writeDetailedCookbookStateReport(out io.Writer, records []*reporting.CookbookRecord) {
 	var (
 		downloadErrors   strings.Builder
 		usageFetchErrors strings.Builder
@@ -196,13 +300,14 @@ func writeDetailedCookbookStateReport(records []*reporting.CookbookRecord) {
 		var strBuilder strings.Builder
 
 		// skip unused cookbooks
-		if len(record.Nodes) == 0 && cookbooksFlags.skipUnused {
+		if len(record.Nodes) == 0 && cookbooks.skipUnused {
 			continue
 		}
 
 		strBuilder.WriteString(fmt.Sprintf("Cookbook: %v (%v)\n", record.Name, record.Version))
 		strBuilder.WriteString(fmt.Sprintf("Violations: %v\n", record.NumOffenses()))
 		strBuilder.WriteString(fmt.Sprintf("Auto correctable: %v\n", record.NumCorrectable()))
+		strBuilder.WriteString(fmt.Sprintf("Secrets found: %v\n", len(record.Secrets)))
 
 		strBuilder.WriteString("Nodes affected: ")
 		if len(record.Nodes) == 0 {
@@ -220,6 +325,12 @@ func writeDetailedCookbookStateReport(records []*reporting.CookbookRecord) {
 				strBuilder.WriteString(fmt.Sprintf("\n\t%s (%t) %s", o.CopName, o.Correctable, o.Message))
 			}
 		}
+		if len(record.Secrets) > 0 {
+			strBuilder.WriteString("\nSecrets:")
+			for _, s := range record.Secrets {
+				strBuilder.WriteString(fmt.Sprintf("\n - %s:%d %s (%s, entropy %.2f)", s.Path, s.Line, s.RuleID, s.Match, s.Entropy))
+			}
+		}
 
 		if record.DownloadError != nil {
 			strBuilder.WriteString("\nERROR: could not download cookbook (see end of report)")
@@ -232,28 +343,27 @@ func writeDetailedCookbookStateReport(records []*reporting.CookbookRecord) {
 			usageFetchErrors.WriteString(fmt.Sprintf(" - %s (%s): %v\n", record.Name, record.Version, record.UsageLookupError))
 		}
 
-		// TODO @afiune write report to disk
-		fmt.Println(strBuilder.String())
+		fmt.Fprintln(out, strBuilder.String())
 	}
 
 	writeErrorBuilders(downloadErrors, cookstyleErrors, usageFetchErrors)
 }
 
-func writeDetailedCSV(records []*reporting.CookbookRecord) {
+func writeDetailedCSV(out io.Writer, records []*reporting.CookbookRecord) {
 	var (
 		strBuilder strings.Builder
 		csvWriter  = csv.NewWriter(&strBuilder)
 	)
 	// table headers
-	csvWriter.Write([]string{"Cookbook Name", "Version", "File", "Offense", "Automatically Correctable", "Message", "Nodes"})
+	csvWriter.Write([]string{"Cookbook Name", "Version", "File", "Offense", "Automatically Correctable", "Message", "Line", "Entropy", "Nodes"})
 
 	for _, record := range records {
 		// skip unused cookbooks
-		if len(record.Nodes) == 0 && cookbooksFlags.skipUnused {
+		if len(record.Nodes) == 0 && cookbooks.skipUnused {
 			continue
 		}
 
-		firstRow := []string{record.Name, record.Version, "", "", "", "", strings.Join(record.Nodes, " ")}
+		firstRow := []string{record.Name, record.Version, "", "", "", "", "", "", strings.Join(record.Nodes, " ")}
 		firstRowPopulated := false
 		for _, file := range record.Files {
 			if len(file.Offenses) == 0 {
@@ -270,11 +380,12 @@ func writeDetailedCSV(records []*reporting.CookbookRecord) {
 					firstRow[4] = "N"
 				}
 				firstRow[5] = firstOffense.Message
+				firstRow[6] = strconv.Itoa(firstOffense.Location.Line)
 				csvWriter.Write(firstRow)
 				firstRowPopulated = true
 			} else {
 				for _, offense := range file.Offenses {
-					row := []string{"", "", "", offense.CopName, "", offense.Message, ""}
+					row := []string{"", "", "", offense.CopName, "", offense.Message, strconv.Itoa(offense.Location.Line), "", ""}
 					if offense.Correctable {
 						row[4] = "Y"
 					} else {
@@ -284,11 +395,95 @@ func writeDetailedCSV(records []*reporting.CookbookRecord) {
 				}
 			}
 		}
+
+		for _, secret := range record.Secrets {
+			row := []string{
+				"", "", secret.Path, "secret:" + secret.RuleID, "N", secret.Match,
+				strconv.Itoa(secret.Line), strconv.FormatFloat(secret.Entropy, 'f', 2, 64), "",
+			}
+			if firstRowPopulated == false {
+				row[0], row[1] = record.Name, record.Version
+				firstRowPopulated = true
+			}
+			csvWriter.Write(row)
+		}
 	}
 	csvWriter.Flush()
 
-	// TODO @afiune write report to disk
-	fmt.Println(strBuilder.String())
+	fmt.Fprintln(out, strBuilder.String())
+}
+
+// cookbookRecordJSON is the JSON rendering of a reporting.CookbookRecord.
+// It exists because CookbookRecord's Download/Cookstyle/UsageLookupError
+// fields are the error interface: encoding/json can't see the unexported
+// fields of the concrete *errors.errorString/*fmt.wrapError values behind
+// them, so marshaling a *CookbookRecord directly renders every error as
+// "{}" and silently drops the one detail a CI consumer needs.
+type cookbookRecordJSON struct {
+	Name    string                    `json:"name"`
+	Version string                    `json:"version"`
+	Files   []*reporting.File         `json:"files"`
+	Nodes   []string                  `json:"nodes"`
+	Secrets []reporting.SecretFinding `json:"secrets,omitempty"`
+
+	DownloadError    string `json:"downloadError,omitempty"`
+	CookstyleError   string `json:"cookstyleError,omitempty"`
+	UsageLookupError string `json:"usageLookupError,omitempty"`
+}
+
+// newCookbookRecordJSON renders record's error fields as their .Error()
+// string so they survive JSON encoding.
+func newCookbookRecordJSON(record *reporting.CookbookRecord) cookbookRecordJSON {
+	j := cookbookRecordJSON{
+		Name:    record.Name,
+		Version: record.Version,
+		Files:   record.Files,
+		Nodes:   record.Nodes,
+		Secrets: record.Secrets,
+	}
+	if record.DownloadError != nil {
+		j.DownloadError = record.DownloadError.Error()
+	}
+	if record.CookstyleError != nil {
+		j.CookstyleError = record.CookstyleError.Error()
+	}
+	if record.UsageLookupError != nil {
+		j.UsageLookupError = record.UsageLookupError.Error()
+	}
+	return j
+}
+
+// writeCookbookStateReportJSON marshals records as a JSON array to out,
+// honoring the --skip-unused flag the same way the other writers do.
+func writeCookbookStateReportJSON(out io.Writer, records []*reporting.CookbookRecord) error {
+	filtered := make([]cookbookRecordJSON, 0, len(records))
+	for _, record := range records {
+		if len(record.Nodes) == 0 && cookbooks.skipUnused {
+			continue
+		}
+		filtered = append(filtered, newCookbookRecordJSON(record))
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(filtered)
+}
+
+// writeCookbookStateReportSARIF renders records as a SARIF 2.1.0 log so the
+// report can be consumed by CI systems that support code-scanning results
+// (GitHub, GitLab, etc), honoring --skip-unused like the other writers.
+func writeCookbookStateReportSARIF(out io.Writer, records []*reporting.CookbookRecord) error {
+	filtered := make([]*reporting.CookbookRecord, 0, len(records))
+	for _, record := range records {
+		if len(record.Nodes) == 0 && cookbooks.skipUnused {
+			continue
+		}
+		filtered = append(filtered, record)
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(reporting.BuildSarifLog(filtered))
 }
 
 func writeNodeReport(records []reporting.NodeReportItem) {
@@ -322,6 +517,59 @@ func writeNodeReport(records []reporting.NodeReportItem) {
 	}
 }
 
+// scanCookbooksForSecrets runs the secret scanner against every downloaded
+// cookbook and attaches its findings to record.Secrets, so the txt/CSV/
+// JSON/SARIF writers can surface them alongside cookstyle offenses.
+func scanCookbooksForSecrets(records []*reporting.CookbookRecord, secretsConfigPath string) error {
+	var cfg *reporting.SecretsConfig
+	if secretsConfigPath != "" {
+		loaded, err := reporting.LoadSecretsConfig(secretsConfigPath)
+		if err != nil {
+			return fmt.Errorf("unable to load --secrets-config: %w", err)
+		}
+		cfg = loaded
+	}
+
+	for _, record := range records {
+		if record.DownloadError != nil || record.LocalPath == "" {
+			continue
+		}
+		findings, err := reporting.ScanForSecrets(record.LocalPath, cfg)
+		if err != nil {
+			return fmt.Errorf("unable to scan cookbook %s (%s) for secrets: %w", record.Name, record.Version, err)
+		}
+		record.Secrets = findings
+	}
+	return nil
+}
+
+func writeFixSummary(results []reporting.FixResult) {
+	var fixed, uploaded, skipped, failed int
+	for _, r := range results {
+		switch {
+		case r.Error != nil:
+			failed++
+			fmt.Printf("%v (%v) FAILED: %v\n", r.Name, r.Version, r.Error)
+		case r.Skipped:
+			skipped++
+			fmt.Printf("%v (%v) SKIPPED: %v\n", r.Name, r.Version, r.SkipReason)
+		default:
+			if r.Fixed {
+				fixed++
+			}
+			if r.Uploaded {
+				uploaded++
+				fmt.Printf("%v (%v) fixed and uploaded\n", r.Name, r.Version)
+			} else {
+				fmt.Printf("%v (%v) fixed (dry-run, not uploaded)\n", r.Name, r.Version)
+			}
+		}
+	}
+
+	fmt.Printf("\nfix summary: %v fixed, %v uploaded, %v skipped, %v failed\n",
+		fixed, uploaded, skipped, failed)
+}
+
 func writeErrorBuilders(errBuilders ...strings.Builder) {
 	firstMsg := true
 	for _, errBldr := range errBuilders {
@@ -0,0 +1,77 @@
+//
+// Copyright 2019 Chef Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reporting
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// chefignore represents the set of glob patterns loaded from one or more
+// chefignore files that should exclude files from a cookbook upload.
+type chefignore struct {
+	patterns []string
+}
+
+// loadChefignore reads the chefignore file at the root of cookbookPath, if
+// any. Chef Infra Server also honors chefignore files nested one level
+// below a cookbooks directory (cookbooks/*/chefignore), so callers that
+// walk a `cookbooks/` directory of multiple cookbooks should load each
+// cookbook's own chefignore independently.
+func loadChefignore(cookbookPath string) (*chefignore, error) {
+	ignore := &chefignore{}
+
+	path := filepath.Join(cookbookPath, "chefignore")
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return ignore, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ignore.patterns = append(ignore.patterns, line)
+	}
+
+	return ignore, scanner.Err()
+}
+
+// Excludes returns true if relPath (relative to the cookbook root) matches
+// any of the loaded chefignore patterns.
+func (c *chefignore) Excludes(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	for _, pattern := range c.patterns {
+		if ok, _ := filepath.Match(pattern, relPath); ok {
+			return true
+		}
+		// chefignore patterns are also matched against the base name,
+		// e.g. "*~" should ignore "templates/default/foo.erb~"
+		if ok, _ := filepath.Match(pattern, filepath.Base(relPath)); ok {
+			return true
+		}
+	}
+	return false
+}
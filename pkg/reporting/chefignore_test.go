@@ -0,0 +1,69 @@
+//
+// Copyright 2019 Chef Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reporting
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadChefignoreMissing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chef-analyze-chefignore-test-")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ignore, err := loadChefignore(dir)
+	if err != nil {
+		t.Fatalf("loadChefignore returned an error: %v", err)
+	}
+	if ignore.Excludes("recipes/default.rb") {
+		t.Errorf("expected no patterns to be loaded when chefignore is missing")
+	}
+}
+
+func TestChefignoreExcludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chef-analyze-chefignore-test-")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	contents := "# comment\nspec/*\n*~\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "chefignore"), []byte(contents), 0644); err != nil {
+		t.Fatalf("unable to write chefignore: %v", err)
+	}
+
+	ignore, err := loadChefignore(dir)
+	if err != nil {
+		t.Fatalf("loadChefignore returned an error: %v", err)
+	}
+
+	cases := map[string]bool{
+		"spec/default_spec.rb":   true,
+		"recipes/default.rb":     false,
+		"templates/default.erb~": true,
+	}
+	for relPath, want := range cases {
+		if got := ignore.Excludes(relPath); got != want {
+			t.Errorf("Excludes(%q) = %t, want %t", relPath, got, want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+//
+// Copyright 2019 Chef Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reporting
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	chef "github.com/go-chef/chef"
+
+	"github.com/chef/go-libs/credentials"
+)
+
+// Reporting bundles the credentials and TLS settings every `report`
+// subcommand needs in order to talk to a Chef Infra Server.
+type Reporting struct {
+	Credentials credentials.Credentials
+	NoSSLVerify bool
+}
+
+// ChefClient is a thin wrapper around a go-chef/chef API client that adds
+// the Cookbooks and Search services the cookbook and node reports are built
+// on top of.
+type ChefClient struct {
+	*chef.Client
+	Cookbooks CookbooksService
+	Search    SearchService
+}
+
+// NewChefClient authenticates against the Chef Infra Server described by
+// cfg.Credentials and returns a client ready to back the cookbook and node
+// reports.
+func NewChefClient(cfg *Reporting) (*ChefClient, error) {
+	rawClient, err := chef.NewClient(&chef.Config{
+		Name:    cfg.Credentials.ClientName,
+		Key:     cfg.Credentials.ClientKey,
+		BaseURL: cfg.Credentials.ChefServerUrl,
+		SkipSSL: cfg.NoSSLVerify,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create a Chef Infra Server client: %w", err)
+	}
+
+	client := &ChefClient{Client: rawClient}
+	client.Cookbooks = &cookbooksService{client: client}
+	client.Search = &searchService{client: client}
+	return client, nil
+}
+
+// doRequest builds and issues an HTTP request against path, relative to the
+// organization base URL the client was configured with, and returns the raw
+// response for the caller to decode and close.
+func (c *ChefClient) doRequest(method, path string, body []byte) (*http.Response, error) {
+	var reader *bytes.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := c.Client.NewRequest(method, path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return c.Client.Do(req, nil)
+}
+
+// Get issues an authenticated GET against path and returns the raw
+// response.
+func (c *ChefClient) Get(path string) (*http.Response, error) {
+	return c.doRequest(http.MethodGet, path, nil)
+}
+
+// PostJSON issues an authenticated POST of a JSON payload against path and
+// returns the raw response.
+func (c *ChefClient) PostJSON(path string, body []byte) (*http.Response, error) {
+	return c.doRequest(http.MethodPost, path, body)
+}
+
+// PutJSON issues an authenticated PUT of a JSON payload against path and
+// returns the raw response.
+func (c *ChefClient) PutJSON(path string, body []byte) (*http.Response, error) {
+	return c.doRequest(http.MethodPut, path, body)
+}
+
+// PutRaw uploads raw, non-JSON bytes to an absolute URL, used to push file
+// contents to the per-checksum URLs the sandbox endpoint returns.
+func (c *ChefClient) PutRaw(url string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-binary")
+
+	resp, err := c.Client.Do(req, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("upload to %s failed with status %s", url, resp.Status)
+	}
+	return nil
+}
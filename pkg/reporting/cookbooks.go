@@ -0,0 +1,360 @@
+//
+// Copyright 2019 Chef Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// OffenseLocation is the 1-indexed line a cookstyle offense was reported
+// against.
+type OffenseLocation struct {
+	Line int
+}
+
+// Offense is a single cookstyle violation found in a cookbook file.
+type Offense struct {
+	CopName     string
+	Correctable bool
+	Message     string
+	Location    OffenseLocation
+}
+
+// File is a single cookbook file cookstyle analyzed, along with every
+// offense found in it.
+type File struct {
+	Path     string
+	Offenses []Offense
+}
+
+// CookbookRecord is everything the cookbook report knows about a single
+// cookbook version once it's made it through the fetch-usage, download,
+// and cookstyle stages of NewCookbooks.
+type CookbookRecord struct {
+	Name    string
+	Version string
+
+	// LocalPath is where the cookbook was downloaded to, empty if
+	// DownloadError is set.
+	LocalPath string
+	Files     []*File
+	Nodes     []string
+	Secrets   []SecretFinding
+
+	DownloadError    error
+	CookstyleError   error
+	UsageLookupError error
+}
+
+// NumOffenses returns the total number of cookstyle offenses found across
+// every file in the cookbook.
+func (r *CookbookRecord) NumOffenses() int {
+	count := 0
+	for _, f := range r.Files {
+		count += len(f.Offenses)
+	}
+	return count
+}
+
+// NumCorrectable returns the number of cookstyle offenses that `cookstyle
+// -a` can fix automatically.
+func (r *CookbookRecord) NumCorrectable() int {
+	count := 0
+	for _, f := range r.Files {
+		for _, o := range f.Offenses {
+			if o.Correctable {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// CookbooksState is the result of running the fetch-usage -> download ->
+// cookstyle pipeline against every cookbook version on a Chef Infra Server.
+type CookbooksState struct {
+	Records []*CookbookRecord
+}
+
+// CookbookVersion identifies a single uploaded cookbook version.
+type CookbookVersion struct {
+	Name    string
+	Version string
+}
+
+// CookbooksService is the subset of the Chef Infra Server cookbooks API
+// the cookbook report and `--fix` depend on.
+type CookbooksService interface {
+	// List returns every cookbook name/version pair uploaded to the server.
+	List() ([]CookbookVersion, error)
+	// Download fetches a cookbook version to a local temporary directory
+	// and returns its path. An empty version downloads the latest
+	// uploaded version and returns the version actually downloaded.
+	Download(name, version string) (localPath, resolvedVersion string, err error)
+	// Upload pushes a locally built cookbook version back to the server.
+	Upload(manifest *UploadManifest, opts UploadOptions) error
+}
+
+// NewCookbooks runs every uploaded cookbook version through a three stage
+// pipeline - fetch-usage, download, cookstyle - via a single runWorkerPool
+// call in which each worker carries its own cookbook through all three
+// stages in sequence. Cookbooks are otherwise independent of one another,
+// so a slow download only delays that cookbook's own cookstyle run, not
+// any other cookbook's; concurrency bounds how many cookbooks move through
+// the pipeline at once. Progress is reported through progress as each
+// stage starts and finishes.
+func NewCookbooks(cookbooks CookbooksService, search SearchService, skipUnused bool, concurrency int, progress Progress) (*CookbooksState, error) {
+	versions, err := cookbooks.List()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list cookbooks: %w", err)
+	}
+
+	records := make(map[string]*CookbookRecord, len(versions))
+	keys := make([]string, 0, len(versions))
+	for _, v := range versions {
+		key := v.Name + "@" + v.Version
+		records[key] = &CookbookRecord{Name: v.Name, Version: v.Version}
+		keys = append(keys, key)
+	}
+
+	runWorkerPool(concurrency, keys, func(key string) {
+		record := records[key]
+		vertex := progress.Vertex(key)
+
+		// fetch-usage: which nodes reference this cookbook version.
+		vertex.Start("fetch-usage")
+		nodes, err := search.NodesUsingCookbook(record.Name, record.Version)
+		if err != nil {
+			record.UsageLookupError = err
+		} else {
+			record.Nodes = nodes
+		}
+		vertex.Complete("fetch-usage", err)
+
+		// download: pull the cookbook version's files locally.
+		vertex.Start("download")
+		localPath, resolvedVersion, err := cookbooks.Download(record.Name, record.Version)
+		if err != nil {
+			record.DownloadError = err
+			vertex.Complete("download", err)
+			return
+		}
+		record.LocalPath = localPath
+		record.Version = resolvedVersion
+		vertex.Complete("download", nil)
+
+		// cookstyle: analyze the cookbook that just downloaded cleanly.
+		vertex.Start("cookstyle")
+		files, err := RunCookstyle(record.LocalPath)
+		if err != nil {
+			record.CookstyleError = err
+			vertex.Complete("cookstyle", err)
+			return
+		}
+		record.Files = files
+		vertex.Complete("cookstyle", nil)
+	})
+
+	state := &CookbooksState{Records: make([]*CookbookRecord, 0, len(keys))}
+	for _, key := range keys {
+		record := records[key]
+		if skipUnused && len(record.Nodes) == 0 {
+			continue
+		}
+		state.Records = append(state.Records, record)
+	}
+	return state, nil
+}
+
+// cookstyleReport mirrors the JSON cookstyle emits with `--format json`.
+type cookstyleReport struct {
+	Files []struct {
+		Path     string `json:"path"`
+		Offenses []struct {
+			CopName     string `json:"cop_name"`
+			Correctable bool   `json:"correctable"`
+			Message     string `json:"message"`
+			Location    struct {
+				StartLine int `json:"start_line"`
+			} `json:"location"`
+		} `json:"offenses"`
+	} `json:"files"`
+}
+
+// RunCookstyle runs `cookstyle --format json` against every file in
+// cookbookPath and returns the offenses found, keyed by file. Like
+// runCookstyleAutocorrect, cookstyle exits non-zero whenever it finds
+// offenses, so only a failure to produce valid JSON output is treated as a
+// hard error.
+func RunCookstyle(cookbookPath string) ([]*File, error) {
+	cmd := exec.Command("cookstyle", "--format", "json", cookbookPath)
+	out, _ := cmd.Output()
+
+	var report cookstyleReport
+	if err := json.Unmarshal(out, &report); err != nil {
+		return nil, fmt.Errorf("unable to parse cookstyle output: %w", err)
+	}
+
+	files := make([]*File, 0, len(report.Files))
+	for _, f := range report.Files {
+		relPath, err := filepath.Rel(cookbookPath, f.Path)
+		if err != nil {
+			relPath = f.Path
+		}
+
+		file := &File{Path: filepath.ToSlash(relPath)}
+		for _, o := range f.Offenses {
+			file.Offenses = append(file.Offenses, Offense{
+				CopName:     o.CopName,
+				Correctable: o.Correctable,
+				Message:     o.Message,
+				Location:    OffenseLocation{Line: o.Location.StartLine},
+			})
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// cookbooksService is the ChefClient-backed implementation of
+// CookbooksService.
+type cookbooksService struct {
+	client *ChefClient
+}
+
+// serverCookbookList is the shape of a GET /cookbooks response: one entry
+// per cookbook name, each listing its uploaded versions.
+type serverCookbookList map[string]struct {
+	Versions []struct {
+		Version string `json:"version"`
+	} `json:"versions"`
+}
+
+func (s *cookbooksService) List() ([]CookbookVersion, error) {
+	resp, err := s.client.Get("cookbooks?num_versions=all")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var list serverCookbookList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("unable to parse cookbooks list: %w", err)
+	}
+
+	var versions []CookbookVersion
+	for name, entry := range list {
+		for _, v := range entry.Versions {
+			versions = append(versions, CookbookVersion{Name: name, Version: v.Version})
+		}
+	}
+	return versions, nil
+}
+
+// serverCookbookDocument is the shape of a GET /cookbooks/<name>/<version>
+// response: one array of {name, path, checksum, url} entries per manifest
+// segment.
+type serverCookbookDocument map[string][]struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	URL  string `json:"url"`
+}
+
+func (s *cookbooksService) Download(name, version string) (string, string, error) {
+	if version == "" {
+		resolved, err := s.latestVersion(name)
+		if err != nil {
+			return "", "", err
+		}
+		version = resolved
+	}
+
+	resp, err := s.client.Get(fmt.Sprintf("cookbooks/%s/%s", name, version))
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	var doc serverCookbookDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return "", "", fmt.Errorf("unable to parse cookbook document for %s (%s): %w", name, version, err)
+	}
+
+	localPath, err := ioutil.TempDir("", fmt.Sprintf("chef-analyze-%s-%s-", name, version))
+	if err != nil {
+		return "", "", err
+	}
+
+	for segment, files := range doc {
+		if segment == "metadata" || segment == "name" || segment == "version" || segment == "frozen?" {
+			continue
+		}
+		for _, f := range files {
+			if err := s.downloadFile(localPath, f.Path, f.URL); err != nil {
+				return "", "", fmt.Errorf("unable to download %s: %w", f.Path, err)
+			}
+		}
+	}
+
+	return localPath, version, nil
+}
+
+func (s *cookbooksService) downloadFile(localPath, relPath, url string) error {
+	resp, err := s.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	dest := filepath.Join(localPath, filepath.FromSlash(relPath))
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dest, contents, 0644)
+}
+
+func (s *cookbooksService) latestVersion(name string) (string, error) {
+	versions, err := s.List()
+	if err != nil {
+		return "", err
+	}
+
+	latest := ""
+	for _, v := range versions {
+		if v.Name == name {
+			latest = v.Version
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("no uploaded versions found for cookbook %s", name)
+	}
+	return latest, nil
+}
+
+func (s *cookbooksService) Upload(manifest *UploadManifest, opts UploadOptions) error {
+	return uploadCookbook(s.client, manifest, opts)
+}
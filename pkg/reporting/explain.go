@@ -0,0 +1,212 @@
+//
+// Copyright 2019 Chef Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reporting
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// SnippetContext is the number of lines of source printed before and
+// after an offense's reported location.
+const SnippetContext = 2
+
+// OffenseExplanation pairs a single offense with a few lines of source
+// read from around its reported location.
+type OffenseExplanation struct {
+	File    string
+	Offense Offense
+	Snippet []string
+	// SnippetStartLine is the 1-indexed line number of Snippet[0].
+	SnippetStartLine int
+}
+
+// CopRollup summarizes every offense reported for a single cop across a
+// cookbook.
+type CopRollup struct {
+	CopName          string
+	Count            int
+	CorrectableCount int
+}
+
+// CorrectableRatio returns the fraction of this cop's offenses that
+// cookstyle can fix automatically, as a value between 0 and 1.
+func (r CopRollup) CorrectableRatio() float64 {
+	if r.Count == 0 {
+		return 0
+	}
+	return float64(r.CorrectableCount) / float64(r.Count)
+}
+
+// CookbookExplanation is the deep-dive view of a single cookbook version
+// produced by `report cookbooks explain`.
+type CookbookExplanation struct {
+	Name    string
+	Version string
+
+	Offenses     []OffenseExplanation
+	CopRollup    []CopRollup
+	Nodes        []string
+	Roles        []string
+	Environments []string
+
+	// LocalPath is where the cookbook was downloaded to, empty if
+	// DownloadError is set.
+	LocalPath string
+
+	DownloadError    error
+	CookstyleError   error
+	UsageLookupError error
+}
+
+// SuggestedFix returns the cookstyle invocation an operator can run by
+// hand to reproduce what `report cookbooks --fix` would do.
+func (e *CookbookExplanation) SuggestedFix() string {
+	return fmt.Sprintf("cookstyle -a %s", e.LocalPath)
+}
+
+// Explain downloads the given cookbook (pinned to version, or the latest
+// uploaded version if version is empty), runs cookstyle against it, and
+// builds a CookbookExplanation describing every offense in detail plus the
+// nodes/roles/environments currently using it.
+func Explain(client *ChefClient, name, version string) (*CookbookExplanation, error) {
+	explanation := &CookbookExplanation{Name: name, Version: version}
+
+	localPath, resolvedVersion, err := client.Cookbooks.Download(name, version)
+	if err != nil {
+		explanation.DownloadError = err
+		return explanation, nil
+	}
+	explanation.Version = resolvedVersion
+	explanation.LocalPath = localPath
+
+	files, err := RunCookstyle(localPath)
+	if err != nil {
+		explanation.CookstyleError = err
+		return explanation, nil
+	}
+
+	rollup := make(map[string]*CopRollup)
+	for _, file := range files {
+		for _, offense := range file.Offenses {
+			snippet, start, err := readSnippet(filepath.Join(localPath, file.Path), offense.Location.Line, SnippetContext)
+			if err != nil {
+				snippet, start = nil, 0
+			}
+			explanation.Offenses = append(explanation.Offenses, OffenseExplanation{
+				File:             file.Path,
+				Offense:          offense,
+				Snippet:          snippet,
+				SnippetStartLine: start,
+			})
+
+			r, ok := rollup[offense.CopName]
+			if !ok {
+				r = &CopRollup{CopName: offense.CopName}
+				rollup[offense.CopName] = r
+			}
+			r.Count++
+			if offense.Correctable {
+				r.CorrectableCount++
+			}
+		}
+	}
+
+	for _, r := range rollup {
+		explanation.CopRollup = append(explanation.CopRollup, *r)
+	}
+	sort.Slice(explanation.CopRollup, func(i, j int) bool {
+		return explanation.CopRollup[i].CopName < explanation.CopRollup[j].CopName
+	})
+
+	usage, err := cookbookUsage(client.Search, name, explanation.Version)
+	if err != nil {
+		explanation.UsageLookupError = err
+	} else {
+		explanation.Nodes = usage.Nodes
+		explanation.Roles = usage.Roles
+		explanation.Environments = usage.Environments
+	}
+
+	return explanation, nil
+}
+
+// cookbookUsageResult is the set of Chef Infra Server objects that
+// reference a cookbook version.
+type cookbookUsageResult struct {
+	Nodes        []string
+	Roles        []string
+	Environments []string
+}
+
+// cookbookUsage queries search for every node, role, and environment that
+// pins name at version.
+func cookbookUsage(search SearchService, name, version string) (*cookbookUsageResult, error) {
+	nodes, err := search.NodesUsingCookbook(name, version)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := search.RolesUsingCookbook(name, version)
+	if err != nil {
+		return nil, err
+	}
+	environments, err := search.EnvironmentsUsingCookbook(name, version)
+	if err != nil {
+		return nil, err
+	}
+	return &cookbookUsageResult{Nodes: nodes, Roles: roles, Environments: environments}, nil
+}
+
+// readSnippet returns up to 2*context+1 lines of path centered on line
+// (1-indexed), along with the 1-indexed line number of the first line
+// returned.
+func readSnippet(path string, line, context int) ([]string, int, error) {
+	if line <= 0 {
+		return nil, 0, fmt.Errorf("no location reported for this offense")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	start := line - context
+	if start < 1 {
+		start = 1
+	}
+	end := line + context
+
+	var snippet []string
+	scanner := bufio.NewScanner(f)
+	current := 0
+	for scanner.Scan() {
+		current++
+		if current < start {
+			continue
+		}
+		if current > end {
+			break
+		}
+		snippet = append(snippet, scanner.Text())
+	}
+
+	return snippet, start, scanner.Err()
+}
@@ -0,0 +1,182 @@
+//
+// Copyright 2019 Chef Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reporting
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// FixOptions controls the behavior of FixCookbooks.
+type FixOptions struct {
+	// Freeze marks the cookbook as frozen once it is uploaded back to the
+	// Chef Infra Server, preventing further uploads of the same version.
+	Freeze bool
+	// DryRun runs cookstyle and builds the upload manifest but never
+	// uploads anything back to the Chef Infra Server.
+	DryRun bool
+	// Force re-uploads a cookbook even if cookstyle didn't find any
+	// auto-correctable offenses.
+	Force bool
+}
+
+// FixResult captures the outcome of attempting to auto-correct and
+// re-upload a single cookbook.
+type FixResult struct {
+	Name       string
+	Version    string
+	Fixed      bool
+	Uploaded   bool
+	Skipped    bool
+	SkipReason string
+	Error      error
+}
+
+// FixCookbooks downloads every cookbook referenced by records, runs
+// `cookstyle -a` against it, bumps its metadata version, and uploads the
+// corrected cookbook back to the Chef Infra Server pointed at by client.
+//
+// Cookbooks that failed to download or failed to run cookstyle during the
+// initial report are skipped, as are cookbooks without any auto-correctable
+// offenses unless opts.Force is set.
+func FixCookbooks(client *ChefClient, records []*CookbookRecord, opts FixOptions) []FixResult {
+	results := make([]FixResult, 0, len(records))
+
+	for _, record := range records {
+		result := FixResult{Name: record.Name, Version: record.Version}
+
+		if record.DownloadError != nil {
+			result.Skipped = true
+			result.SkipReason = "cookbook could not be downloaded"
+			results = append(results, result)
+			continue
+		}
+		if record.CookstyleError != nil {
+			result.Skipped = true
+			result.SkipReason = "cookstyle failed to analyze the cookbook"
+			results = append(results, result)
+			continue
+		}
+		if record.NumCorrectable() == 0 && !opts.Force {
+			result.Skipped = true
+			result.SkipReason = "no auto-correctable offenses"
+			results = append(results, result)
+			continue
+		}
+
+		if err := fixOne(client, record, opts, &result); err != nil {
+			result.Error = err
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// fixOne runs cookstyle -a against the downloaded cookbook located at
+// record.LocalPath, bumps its metadata version, and (unless opts.DryRun)
+// uploads it back to the Chef Infra Server.
+func fixOne(client *ChefClient, record *CookbookRecord, opts FixOptions, result *FixResult) error {
+	if record.LocalPath == "" {
+		return fmt.Errorf("cookbook %s (%s) has no local copy to fix", record.Name, record.Version)
+	}
+
+	if err := runCookstyleAutocorrect(record.LocalPath); err != nil {
+		return fmt.Errorf("unable to auto-correct cookbook %s (%s): %w", record.Name, record.Version, err)
+	}
+	result.Fixed = true
+
+	newVersion, err := bumpMetadataVersion(record.LocalPath)
+	if err != nil {
+		return fmt.Errorf("unable to bump metadata version for cookbook %s (%s): %w", record.Name, record.Version, err)
+	}
+	result.Version = newVersion
+
+	manifest, err := buildUploadManifest(record.Name, newVersion, record.LocalPath)
+	if err != nil {
+		return fmt.Errorf("unable to build upload manifest for cookbook %s (%s): %w", record.Name, newVersion, err)
+	}
+
+	if opts.DryRun {
+		return nil
+	}
+
+	if err := client.Cookbooks.Upload(manifest, UploadOptions{Freeze: opts.Freeze}); err != nil {
+		return fmt.Errorf("unable to upload cookbook %s (%s): %w", record.Name, newVersion, err)
+	}
+	result.Uploaded = true
+
+	return nil
+}
+
+// runCookstyleAutocorrect shells out to `cookstyle -a` against the
+// directory a cookbook was downloaded to, applying every safe
+// auto-correction cookstyle knows about.
+func runCookstyleAutocorrect(cookbookPath string) error {
+	cmd := exec.Command("cookstyle", "-a", cookbookPath)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		// cookstyle exits non-zero when it still finds uncorrectable
+		// offenses after fixing what it can, so only treat execution
+		// failures (binary missing, panics, etc) as hard errors.
+		if _, ok := err.(*exec.ExitError); !ok {
+			return fmt.Errorf("%w: %s", err, out)
+		}
+	}
+	return nil
+}
+
+// bumpMetadataVersion increments the patch component of a cookbook's
+// metadata.rb/metadata.json version and returns the new version string.
+func bumpMetadataVersion(cookbookPath string) (string, error) {
+	metadataRb := filepath.Join(cookbookPath, "metadata.rb")
+	if _, err := os.Stat(metadataRb); err == nil {
+		return bumpMetadataRbVersion(metadataRb)
+	}
+
+	metadataJSON := filepath.Join(cookbookPath, "metadata.json")
+	if _, err := os.Stat(metadataJSON); err == nil {
+		return bumpMetadataJSONVersion(metadataJSON)
+	}
+
+	return "", fmt.Errorf("no metadata.rb or metadata.json found in %s", cookbookPath)
+}
+
+func bumpMetadataRbVersion(path string) (string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	current, line := extractMetadataRbVersion(string(contents))
+	if current == "" {
+		return "", fmt.Errorf("unable to find a 'version' entry in %s", path)
+	}
+
+	next := incrementPatch(current)
+	updated := replaceMetadataRbVersionLine(string(contents), line, next)
+
+	if err := ioutil.WriteFile(path, []byte(updated), 0644); err != nil {
+		return "", err
+	}
+
+	return next, nil
+}
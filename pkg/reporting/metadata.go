@@ -0,0 +1,156 @@
+//
+// Copyright 2019 Chef Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var metadataRbVersionRe = regexp.MustCompile(`(?m)^(\s*version\s+)['"]([0-9]+\.[0-9]+\.[0-9]+)['"]([ \t]*#.*)?$`)
+
+// extractMetadataRbVersion returns the version string declared in a
+// metadata.rb file along with the full matched line, or two empty
+// strings if no version entry was found.
+func extractMetadataRbVersion(contents string) (version, line string) {
+	match := metadataRbVersionRe.FindStringSubmatch(contents)
+	if match == nil {
+		return "", ""
+	}
+	return match[2], match[0]
+}
+
+// replaceMetadataRbVersionLine swaps the version declared on line for
+// next inside contents.
+func replaceMetadataRbVersionLine(contents, line, next string) string {
+	replacement := metadataRbVersionRe.ReplaceAllString(line, "${1}'"+next+"'${3}")
+	return strings.Replace(contents, line, replacement, 1)
+}
+
+// incrementPatch bumps the patch component of a semantic version string,
+// e.g. "1.2.3" becomes "1.2.4".
+func incrementPatch(version string) string {
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return version
+	}
+	patch, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return version
+	}
+	parts[2] = strconv.Itoa(patch + 1)
+	return strings.Join(parts, ".")
+}
+
+// bumpMetadataJSONVersion increments the patch component of the version
+// field in a generated metadata.json file.
+func bumpMetadataJSONVersion(path string) (string, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return "", err
+	}
+
+	current, _ := doc["version"].(string)
+	next := incrementPatch(current)
+	doc["version"] = next
+
+	updated, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	if err := ioutil.WriteFile(path, updated, 0644); err != nil {
+		return "", err
+	}
+
+	return next, nil
+}
+
+// metadataRbStringFields are the metadata.rb DSL methods that declare a
+// single string value, e.g. `maintainer 'Chef Software'`, mapped to the
+// key they're rendered under in a cookbook_version's "metadata" document.
+var metadataRbStringFields = map[string]string{
+	"maintainer":       "maintainer",
+	"maintainer_email": "maintainer_email",
+	"license":          "license",
+	"description":      "description",
+	"long_description": "long_description",
+	"source_url":       "source_url",
+	"issues_url":       "issues_url",
+}
+
+var metadataRbStringFieldRe = regexp.MustCompile(`(?m)^\s*(\w+)\s+['"]([^'"]*)['"]\s*$`)
+
+// metadataRbDependsRe matches a `depends 'name', 'constraint'` line; the
+// constraint is optional, matching a dependency with no version pin.
+var metadataRbDependsRe = regexp.MustCompile(`(?m)^\s*depends\s+['"]([^'"]+)['"](?:\s*,\s*['"]([^'"]*)['"])?\s*$`)
+
+// buildMetadataDocument reads a cookbook's metadata.rb or metadata.json
+// and renders it into the "metadata" sub-document a Chef Infra Server
+// cookbook_version upload requires, with name and version overridden to
+// match what's actually being uploaded.
+func buildMetadataDocument(cookbookPath, name, version string) (map[string]interface{}, error) {
+	jsonPath := filepath.Join(cookbookPath, "metadata.json")
+	if raw, err := ioutil.ReadFile(jsonPath); err == nil {
+		var doc map[string]interface{}
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", jsonPath, err)
+		}
+		doc["name"] = name
+		doc["version"] = version
+		return doc, nil
+	}
+
+	rbPath := filepath.Join(cookbookPath, "metadata.rb")
+	contents, err := ioutil.ReadFile(rbPath)
+	if err != nil {
+		return nil, fmt.Errorf("no metadata.rb or metadata.json found in %s", cookbookPath)
+	}
+
+	doc := map[string]interface{}{
+		"name":    name,
+		"version": version,
+	}
+
+	for _, match := range metadataRbStringFieldRe.FindAllStringSubmatch(string(contents), -1) {
+		key, ok := metadataRbStringFields[match[1]]
+		if !ok {
+			continue
+		}
+		doc[key] = match[2]
+	}
+
+	dependencies := map[string]string{}
+	for _, match := range metadataRbDependsRe.FindAllStringSubmatch(string(contents), -1) {
+		dependencies[match[1]] = match[2]
+	}
+	if len(dependencies) > 0 {
+		doc["dependencies"] = dependencies
+	}
+
+	return doc, nil
+}
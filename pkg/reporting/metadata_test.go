@@ -0,0 +1,46 @@
+//
+// Copyright 2019 Chef Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reporting
+
+import "testing"
+
+func TestExtractMetadataRbVersion(t *testing.T) {
+	cases := map[string]string{
+		"name 'x'\nversion '1.2.3'\n":                 "1.2.3",
+		"name 'x'\nversion '1.2.3' # managed by CI\n": "1.2.3",
+		"name 'x'\nversion '1.2.3'   \n":              "1.2.3",
+		"name 'x'\n":                                  "",
+	}
+	for contents, want := range cases {
+		got, _ := extractMetadataRbVersion(contents)
+		if got != want {
+			t.Errorf("extractMetadataRbVersion(%q) = %q, want %q", contents, got, want)
+		}
+	}
+}
+
+func TestReplaceMetadataRbVersionLinePreservesTrailingComment(t *testing.T) {
+	contents := "name 'x'\nversion '1.2.3' # managed by CI\n"
+	_, line := extractMetadataRbVersion(contents)
+
+	updated := replaceMetadataRbVersionLine(contents, line, "1.2.4")
+
+	want := "name 'x'\nversion '1.2.4' # managed by CI\n"
+	if updated != want {
+		t.Errorf("replaceMetadataRbVersionLine(...) = %q, want %q", updated, want)
+	}
+}
@@ -0,0 +1,54 @@
+//
+// Copyright 2019 Chef Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reporting
+
+import "sync"
+
+// DefaultConcurrency is used when callers don't specify a worker count,
+// e.g. NewCookbooks(..., 0, progress).
+const DefaultConcurrency = 4
+
+// runWorkerPool fans work out across concurrency goroutines, one per item
+// in items, and blocks until every item has been processed. A concurrency
+// of 0 or less falls back to DefaultConcurrency.
+//
+// This backs the cookbook report's fetch-usage -> download -> cookstyle
+// pipeline: NewCookbooks makes a single runWorkerPool call in which each
+// worker runs all three stages for its own cookbook, so a slow cookbook
+// download only blocks that cookbook's own cookstyle run, not every other
+// cookbook's.
+func runWorkerPool(concurrency int, items []string, fn func(name string)) {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for _, item := range items {
+		item := item
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(item)
+		}()
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,235 @@
+//
+// Copyright 2019 Chef Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reporting
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// VertexStatus is the state of a single sub-step of a cookbook's pipeline
+// vertex (fetch-usage, download, cookstyle).
+type VertexStatus int
+
+const (
+	// StatusPending means the step hasn't started yet.
+	StatusPending VertexStatus = iota
+	// StatusRunning means the step is in progress.
+	StatusRunning
+	// StatusDone means the step finished without error.
+	StatusDone
+	// StatusError means the step finished with an error.
+	StatusError
+)
+
+// Progress receives updates from the cookbook pipeline as cookbooks are
+// fetched, downloaded, and analyzed, and renders them to the user. A
+// Progress is safe for concurrent use by multiple pipeline stages.
+type Progress interface {
+	// Vertex returns the tracker for a single cookbook, creating it the
+	// first time it's requested.
+	Vertex(name string) Vertex
+	// Done signals that the pipeline has finished and the progress writer
+	// should render its final state.
+	Done()
+}
+
+// Vertex tracks the sub-steps of a single cookbook moving through the
+// fetch-usage -> download -> cookstyle pipeline.
+type Vertex interface {
+	// Start marks a named step as running.
+	Start(step string)
+	// Complete marks a named step as finished, successfully if err is nil.
+	Complete(step string, err error)
+}
+
+// vertexState is the shared, mutex-guarded state a Progress implementation
+// renders from.
+type vertexState struct {
+	name    string
+	steps   []string
+	status  map[string]VertexStatus
+	errs    map[string]error
+	started time.Time
+}
+
+// NewProgress returns a Progress that renders an in-place updating TTY view
+// when out is a terminal, or a plain, append-only log otherwise. Passing
+// forcePlain true always returns the plain-text view, regardless of
+// whether out is a terminal (the --no-progress flag).
+func NewProgress(out io.Writer, isTTY, forcePlain bool) Progress {
+	if isTTY && !forcePlain {
+		return &ttyProgress{out: out, vertices: make(map[string]*vertexState)}
+	}
+	return &plainProgress{out: out}
+}
+
+// plainProgress renders one line per step transition, suitable for
+// non-interactive output (CI logs, redirected files).
+type plainProgress struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (p *plainProgress) Vertex(name string) Vertex {
+	return &plainVertex{name: name, progress: p}
+}
+
+func (p *plainProgress) Done() {}
+
+type plainVertex struct {
+	name     string
+	progress *plainProgress
+}
+
+func (v *plainVertex) Start(step string) {
+	v.progress.mu.Lock()
+	defer v.progress.mu.Unlock()
+	fmt.Fprintf(v.progress.out, "%s: %s started\n", v.name, step)
+}
+
+func (v *plainVertex) Complete(step string, err error) {
+	v.progress.mu.Lock()
+	defer v.progress.mu.Unlock()
+	if err != nil {
+		fmt.Fprintf(v.progress.out, "%s: %s failed: %v\n", v.name, step, err)
+		return
+	}
+	fmt.Fprintf(v.progress.out, "%s: %s done\n", v.name, step)
+}
+
+// ttyProgress renders every tracked cookbook as an in-place updating line,
+// redrawing the whole block every time a step transitions.
+type ttyProgress struct {
+	mu       sync.Mutex
+	out      io.Writer
+	order    []string
+	vertices map[string]*vertexState
+	rendered int
+	frame    int
+	done     bool
+}
+
+// spinnerFrames are the animation frames for the global spinner shown
+// above the per-cookbook lines, advanced by one on every render.
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+func (p *ttyProgress) Vertex(name string) Vertex {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.vertices[name]; !ok {
+		p.vertices[name] = &vertexState{
+			name:    name,
+			status:  make(map[string]VertexStatus),
+			errs:    make(map[string]error),
+			started: time.Now(),
+		}
+		p.order = append(p.order, name)
+	}
+	return &ttyVertex{name: name, progress: p}
+}
+
+func (p *ttyProgress) Done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done = true
+	p.render()
+}
+
+// render clears the previously drawn block and redraws a global status
+// line (an animated spinner, or "done" once Done has been called)
+// followed by one line per tracked cookbook, sorted alphabetically for a
+// stable layout.
+func (p *ttyProgress) render() {
+	for i := 0; i < p.rendered; i++ {
+		fmt.Fprint(p.out, "\033[1A\033[2K")
+	}
+
+	names := make([]string, len(p.order))
+	copy(names, p.order)
+	sort.Strings(names)
+
+	if p.done {
+		fmt.Fprintf(p.out, "done analyzing %d cookbook(s)\n", len(names))
+	} else {
+		frame := spinnerFrames[p.frame%len(spinnerFrames)]
+		p.frame++
+		fmt.Fprintf(p.out, "%s analyzing %d cookbook(s)...\n", frame, len(names))
+	}
+
+	for _, name := range names {
+		v := p.vertices[name]
+		fmt.Fprintf(p.out, "%-40s %s  %s\n", v.name, vertexSummary(v), time.Since(v.started).Round(time.Second))
+	}
+	p.rendered = len(names) + 1
+}
+
+// vertexSummary renders the fetch-usage/download/cookstyle step statuses
+// as a compact, fixed-order string, e.g. "[fetch-usage:done download:running cookstyle:pending]".
+func vertexSummary(v *vertexState) string {
+	order := []string{"fetch-usage", "download", "cookstyle"}
+	out := "["
+	for i, step := range order {
+		if i > 0 {
+			out += " "
+		}
+		out += step + ":" + statusSymbol(v.status[step])
+	}
+	return out + "]"
+}
+
+func statusSymbol(s VertexStatus) string {
+	switch s {
+	case StatusRunning:
+		return "running"
+	case StatusDone:
+		return "done"
+	case StatusError:
+		return "error"
+	default:
+		return "pending"
+	}
+}
+
+type ttyVertex struct {
+	name     string
+	progress *ttyProgress
+}
+
+func (v *ttyVertex) Start(step string) {
+	v.progress.mu.Lock()
+	defer v.progress.mu.Unlock()
+	v.progress.vertices[v.name].status[step] = StatusRunning
+	v.progress.render()
+}
+
+func (v *ttyVertex) Complete(step string, err error) {
+	v.progress.mu.Lock()
+	defer v.progress.mu.Unlock()
+	state := v.progress.vertices[v.name]
+	if err != nil {
+		state.status[step] = StatusError
+		state.errs[step] = err
+	} else {
+		state.status[step] = StatusDone
+	}
+	v.progress.render()
+}
@@ -0,0 +1,217 @@
+//
+// Copyright 2019 Chef Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reporting
+
+import "fmt"
+
+// SarifVersion is the SARIF schema version this package emits.
+const SarifVersion = "2.1.0"
+
+// SarifSchema is the published schema URI referenced by every SARIF log
+// chef-analyze produces.
+const SarifSchema = "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json"
+
+// SarifLog is the root object of a SARIF 2.1.0 log file.
+type SarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SarifRun `json:"runs"`
+}
+
+// SarifRun describes a single invocation of a single analysis tool.
+type SarifRun struct {
+	Tool    SarifTool     `json:"tool"`
+	Results []SarifResult `json:"results"`
+}
+
+// SarifTool identifies Cookstyle as the tool that produced the results and
+// lists every cop encountered as a rule.
+type SarifTool struct {
+	Driver SarifDriver `json:"driver"`
+}
+
+// SarifDriver carries the tool metadata and its rule (cop) definitions.
+type SarifDriver struct {
+	Name           string                     `json:"name"`
+	InformationURI string                     `json:"informationUri"`
+	Rules          []SarifReportingDescriptor `json:"rules"`
+}
+
+// SarifReportingDescriptor describes a single Cookstyle cop as a SARIF
+// rule.
+type SarifReportingDescriptor struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// SarifResult is a single offense reported against a location in a
+// cookbook file.
+type SarifResult struct {
+	RuleID     string                `json:"ruleId"`
+	Level      string                `json:"level"`
+	Message    SarifMessage          `json:"message"`
+	Locations  []SarifLocation       `json:"locations"`
+	Properties SarifResultProperties `json:"properties"`
+}
+
+// SarifMessage wraps the human readable text of a result.
+type SarifMessage struct {
+	Text string `json:"text"`
+}
+
+// SarifLocation points at the file an offense was found in.
+type SarifLocation struct {
+	PhysicalLocation SarifPhysicalLocation `json:"physicalLocation"`
+}
+
+// SarifPhysicalLocation identifies the artifact (file) a result applies to.
+type SarifPhysicalLocation struct {
+	ArtifactLocation SarifArtifactLocation `json:"artifactLocation"`
+}
+
+// SarifArtifactLocation is the URI of the file an offense was found in,
+// relative to the cookbook it came from.
+type SarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// SarifResultProperties carries chef-analyze specific metadata that
+// doesn't have a dedicated SARIF field.
+type SarifResultProperties struct {
+	AutoCorrectable bool `json:"autoCorrectable"`
+}
+
+// cookstyleLevel maps a cop's category, as encoded in its name
+// (Chef/Correctness, Chef/Security, Chef/Style, ...), to a SARIF result
+// level.
+func cookstyleLevel(copName string) string {
+	switch {
+	case hasSegment(copName, "Security"):
+		return "error"
+	case hasSegment(copName, "Correctness"), hasSegment(copName, "Deprecations"):
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+func hasSegment(copName, segment string) bool {
+	for _, part := range splitCopName(copName) {
+		if part == segment {
+			return true
+		}
+	}
+	return false
+}
+
+func splitCopName(copName string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(copName); i++ {
+		if copName[i] == '/' {
+			parts = append(parts, copName[start:i])
+			start = i + 1
+		}
+	}
+	parts = append(parts, copName[start:])
+	return parts
+}
+
+// BuildSarifLog renders records as a SARIF 2.1.0 log with a single run,
+// one reportingDescriptor per distinct cop encountered, and one result per
+// offense.
+func BuildSarifLog(records []*CookbookRecord) *SarifLog {
+	run := SarifRun{
+		Tool: SarifTool{
+			Driver: SarifDriver{
+				Name:           "Cookstyle",
+				InformationURI: "https://docs.chef.io/workstation/cookstyle/",
+			},
+		},
+	}
+
+	seenCops := make(map[string]bool)
+
+	for _, record := range records {
+		for _, file := range record.Files {
+			uri := fmt.Sprintf("%s/%s/%s", record.Name, record.Version, file.Path)
+			for _, offense := range file.Offenses {
+				if !seenCops[offense.CopName] {
+					seenCops[offense.CopName] = true
+					run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, SarifReportingDescriptor{
+						ID:   offense.CopName,
+						Name: offense.CopName,
+					})
+				}
+
+				run.Results = append(run.Results, SarifResult{
+					RuleID: offense.CopName,
+					Level:  cookstyleLevel(offense.CopName),
+					Message: SarifMessage{
+						Text: offense.Message,
+					},
+					Locations: []SarifLocation{
+						{
+							PhysicalLocation: SarifPhysicalLocation{
+								ArtifactLocation: SarifArtifactLocation{URI: uri},
+							},
+						},
+					},
+					Properties: SarifResultProperties{
+						AutoCorrectable: offense.Correctable,
+					},
+				})
+			}
+		}
+
+		for _, secret := range record.Secrets {
+			ruleID := "secret/" + secret.RuleID
+			if !seenCops[ruleID] {
+				seenCops[ruleID] = true
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, SarifReportingDescriptor{
+					ID:   ruleID,
+					Name: ruleID,
+				})
+			}
+
+			uri := fmt.Sprintf("%s/%s/%s", record.Name, record.Version, secret.Path)
+			run.Results = append(run.Results, SarifResult{
+				RuleID: ruleID,
+				Level:  "error",
+				Message: SarifMessage{
+					Text: fmt.Sprintf("potential hardcoded secret (entropy %.2f): %s", secret.Entropy, secret.Match),
+				},
+				Locations: []SarifLocation{
+					{
+						PhysicalLocation: SarifPhysicalLocation{
+							ArtifactLocation: SarifArtifactLocation{URI: uri},
+						},
+					},
+				},
+				Properties: SarifResultProperties{
+					AutoCorrectable: false,
+				},
+			})
+		}
+	}
+
+	return &SarifLog{
+		Schema:  SarifSchema,
+		Version: SarifVersion,
+		Runs:    []SarifRun{run},
+	}
+}
@@ -0,0 +1,302 @@
+//
+// Copyright 2019 Chef Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SearchService is the subset of the Chef Infra Server search/roles/
+// environments APIs the cookbook report and `report cookbooks explain`
+// depend on to find which nodes, roles, and environments use a given
+// cookbook version.
+type SearchService interface {
+	// NodesUsingCookbook returns the fqdn of every node whose last Chef
+	// Infra Client run applied name at exactly version.
+	NodesUsingCookbook(name, version string) ([]string, error)
+	// RolesUsingCookbook returns every role whose run_list references
+	// name, directly or through a wrapper cookbook/recipe.
+	RolesUsingCookbook(name, version string) ([]string, error)
+	// EnvironmentsUsingCookbook returns every environment that pins name
+	// to version via its cookbook_versions constraints.
+	EnvironmentsUsingCookbook(name, version string) ([]string, error)
+}
+
+type searchService struct {
+	client *ChefClient
+}
+
+// nodeSearchRow is the subset of a partial search node document this
+// package needs: its fqdn and the exact cookbook versions its last Chef
+// Infra Client run reported via automatic attributes.
+type nodeSearchRow struct {
+	Data struct {
+		FQDN      string                    `json:"fqdn"`
+		Cookbooks map[string]cookbookUsedAt `json:"cookbooks"`
+	} `json:"data"`
+}
+
+type cookbookUsedAt struct {
+	Version string `json:"version"`
+}
+
+type partialSearchResult struct {
+	Rows []nodeSearchRow `json:"rows"`
+}
+
+// NodesUsingCookbook runs a partial search for every node's fqdn and
+// reported cookbook versions, and returns the fqdn of every node whose
+// "cookbooks" automatic attribute pins name to version.
+func (s *searchService) NodesUsingCookbook(name, version string) ([]string, error) {
+	payload, err := json.Marshal(map[string][]string{
+		"fqdn":      {"fqdn"},
+		"cookbooks": {"cookbooks"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.PostJSON("search/node?q=*:*", payload)
+	if err != nil {
+		return nil, fmt.Errorf("unable to search nodes: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result partialSearchResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("unable to parse node search results: %w", err)
+	}
+
+	var nodes []string
+	for _, row := range result.Rows {
+		used, ok := row.Data.Cookbooks[name]
+		if ok && used.Version == version {
+			nodes = append(nodes, row.Data.FQDN)
+		}
+	}
+	return nodes, nil
+}
+
+// serverRole is the subset of a GET /roles/<name> document this package
+// needs.
+type serverRole struct {
+	Name    string   `json:"name"`
+	RunList []string `json:"run_list"`
+}
+
+// RolesUsingCookbook lists every role and returns the ones whose run_list
+// references a recipe from name, e.g. "recipe[name::default]" or
+// "recipe[name]". Roles don't pin an exact cookbook version themselves, so
+// version is accepted for interface symmetry with NodesUsingCookbook and
+// EnvironmentsUsingCookbook but isn't used to filter the result.
+func (s *searchService) RolesUsingCookbook(name, _ string) ([]string, error) {
+	roleNames, err := s.listNames("roles")
+	if err != nil {
+		return nil, fmt.Errorf("unable to list roles: %w", err)
+	}
+
+	var roles []string
+	for _, roleName := range roleNames {
+		resp, err := s.client.Get("roles/" + roleName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch role %s: %w", roleName, err)
+		}
+
+		var role serverRole
+		err = json.NewDecoder(resp.Body).Decode(&role)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse role %s: %w", roleName, err)
+		}
+
+		if runListReferencesCookbook(role.RunList, name) {
+			roles = append(roles, roleName)
+		}
+	}
+	return roles, nil
+}
+
+// serverEnvironment is the subset of a GET /environments/<name> document
+// this package needs.
+type serverEnvironment struct {
+	Name             string            `json:"name"`
+	CookbookVersions map[string]string `json:"cookbook_versions"`
+}
+
+// EnvironmentsUsingCookbook lists every environment and returns the ones
+// whose cookbook_versions constraint for name is satisfied by version,
+// e.g. a constraint of "~> 1.2" is satisfied by version "1.2.3".
+func (s *searchService) EnvironmentsUsingCookbook(name, version string) ([]string, error) {
+	envNames, err := s.listNames("environments")
+	if err != nil {
+		return nil, fmt.Errorf("unable to list environments: %w", err)
+	}
+
+	var environments []string
+	for _, envName := range envNames {
+		resp, err := s.client.Get("environments/" + envName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch environment %s: %w", envName, err)
+		}
+
+		var env serverEnvironment
+		err = json.NewDecoder(resp.Body).Decode(&env)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse environment %s: %w", envName, err)
+		}
+
+		constraint, ok := env.CookbookVersions[name]
+		if !ok {
+			continue
+		}
+
+		satisfied, err := constraintSatisfiedBy(constraint, version)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse cookbook_versions constraint %q for environment %s: %w", constraint, envName, err)
+		}
+		if satisfied {
+			environments = append(environments, envName)
+		}
+	}
+	return environments, nil
+}
+
+// cookbookVersionConstraintRe splits a cookbook_versions constraint into
+// its optional operator (defaulting to "=") and version, e.g. "~> 1.2",
+// ">= 1.0.0", or a bare "1.2.3".
+var cookbookVersionConstraintRe = regexp.MustCompile(`^\s*(=|>=|<=|>|<|~>)?\s*([0-9]+(?:\.[0-9]+){0,2})\s*$`)
+
+// constraintSatisfiedBy reports whether version satisfies an environment's
+// cookbook_versions constraint, honoring the operators Chef Infra supports:
+// "=", ">", ">=", "<", "<=", and the pessimistic operator "~>" ("~> 1.2"
+// means ">= 1.2.0, < 2.0.0"; "~> 1.2.3" means ">= 1.2.3, < 1.3.0").
+func constraintSatisfiedBy(constraint, version string) (bool, error) {
+	match := cookbookVersionConstraintRe.FindStringSubmatch(constraint)
+	if match == nil {
+		return false, fmt.Errorf("unrecognized version constraint %q", constraint)
+	}
+	operator, constraintVersion := match[1], match[2]
+	if operator == "" {
+		operator = "="
+	}
+
+	want, err := parseVersion(constraintVersion)
+	if err != nil {
+		return false, err
+	}
+	have, err := parseVersion(version)
+	if err != nil {
+		return false, err
+	}
+
+	switch operator {
+	case "=":
+		return compareVersions(have, want) == 0, nil
+	case ">":
+		return compareVersions(have, want) > 0, nil
+	case ">=":
+		return compareVersions(have, want) >= 0, nil
+	case "<":
+		return compareVersions(have, want) < 0, nil
+	case "<=":
+		return compareVersions(have, want) <= 0, nil
+	case "~>":
+		upperBound := want
+		if strings.Count(constraintVersion, ".") >= 2 {
+			// "~> 1.2.3" allows patch-level changes: >= 1.2.3, < 1.3.0.
+			upperBound = [3]int{want[0], want[1] + 1, 0}
+		} else {
+			// "~> 1.2" allows minor-level changes: >= 1.2.0, < 2.0.0.
+			upperBound = [3]int{want[0] + 1, 0, 0}
+		}
+		return compareVersions(have, want) >= 0 && compareVersions(have, upperBound) < 0, nil
+	default:
+		return false, fmt.Errorf("unsupported version constraint operator %q", operator)
+	}
+}
+
+// parseVersion parses a "major[.minor[.patch]]" version string, defaulting
+// any missing components to 0.
+func parseVersion(version string) ([3]int, error) {
+	var parsed [3]int
+	parts := strings.SplitN(version, ".", 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return parsed, fmt.Errorf("invalid version %q: %w", version, err)
+		}
+		parsed[i] = n
+	}
+	return parsed, nil
+}
+
+// compareVersions returns -1, 0, or 1 if a is less than, equal to, or
+// greater than b.
+func compareVersions(a, b [3]int) int {
+	for i := 0; i < 3; i++ {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// listNames returns the keys of a GET /<index> response (roles,
+// environments, ...), which the Chef Infra Server renders as a
+// name -> url map.
+func (s *searchService) listNames(index string) ([]string, error) {
+	resp, err := s.client.Get(index)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var listing map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&listing); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(listing))
+	for name := range listing {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// runListReferencesCookbook returns true if any entry in runList is a
+// recipe or role reference that would pull in cookbook.
+func runListReferencesCookbook(runList []string, cookbook string) bool {
+	for _, entry := range runList {
+		if !strings.HasPrefix(entry, "recipe[") {
+			continue
+		}
+		ref := strings.TrimSuffix(strings.TrimPrefix(entry, "recipe["), "]")
+		recipeCookbook := strings.SplitN(ref, "::", 2)[0]
+		if recipeCookbook == cookbook {
+			return true
+		}
+	}
+	return false
+}
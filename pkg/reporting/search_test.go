@@ -0,0 +1,74 @@
+//
+// Copyright 2019 Chef Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reporting
+
+import "testing"
+
+func TestConstraintSatisfiedBy(t *testing.T) {
+	cases := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"= 1.2.3", "1.2.3", true},
+		{"= 1.2.3", "1.2.4", false},
+		{"1.2.3", "1.2.3", true}, // bare version implies "="
+		{"> 1.2.3", "1.2.4", true},
+		{"> 1.2.3", "1.2.3", false},
+		{">= 1.2.3", "1.2.3", true},
+		{"< 2.0.0", "1.9.9", true},
+		{"< 2.0.0", "2.0.0", false},
+		{"<= 2.0.0", "2.0.0", true},
+		{"~> 1.2", "1.2.3", true},
+		{"~> 1.2", "1.3.0", true},
+		{"~> 1.2", "2.0.0", false},
+		{"~> 1.2.3", "1.2.9", true},
+		{"~> 1.2.3", "1.3.0", false},
+		{"~> 1.2.3", "1.2.2", false},
+	}
+
+	for _, c := range cases {
+		got, err := constraintSatisfiedBy(c.constraint, c.version)
+		if err != nil {
+			t.Errorf("constraintSatisfiedBy(%q, %q) returned an error: %v", c.constraint, c.version, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("constraintSatisfiedBy(%q, %q) = %t, want %t", c.constraint, c.version, got, c.want)
+		}
+	}
+}
+
+func TestConstraintSatisfiedByInvalid(t *testing.T) {
+	if _, err := constraintSatisfiedBy("not a constraint", "1.2.3"); err == nil {
+		t.Error("expected an error for an unrecognized constraint, got nil")
+	}
+}
+
+func TestRunListReferencesCookbook(t *testing.T) {
+	runList := []string{"recipe[example::default]", "recipe[other]", "role[base]"}
+
+	if !runListReferencesCookbook(runList, "example") {
+		t.Error("expected run_list to reference cookbook example via example::default")
+	}
+	if !runListReferencesCookbook(runList, "other") {
+		t.Error("expected run_list to reference cookbook other")
+	}
+	if runListReferencesCookbook(runList, "missing") {
+		t.Error("expected run_list not to reference cookbook missing")
+	}
+}
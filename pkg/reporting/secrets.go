@@ -0,0 +1,311 @@
+//
+// Copyright 2019 Chef Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reporting
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// MinSecretEntropy is the Shannon entropy (bits per character) above which
+// a high-entropy-string candidate is reported, absent a rule-specific
+// override.
+const MinSecretEntropy = 4.2
+
+// minHighEntropyCandidateLength is the shortest token the generic
+// high-entropy rule will consider; shorter tokens produce too many false
+// positives regardless of entropy.
+const minHighEntropyCandidateLength = 20
+
+// SecretFinding is a single hardcoded credential found in a cookbook file.
+// It's surfaced through CookbookRecord.Secrets alongside the usual
+// cookstyle Offenses, and is always treated as non-auto-correctable.
+type SecretFinding struct {
+	RuleID  string
+	Path    string
+	Line    int
+	Match   string // redacted
+	Entropy float64
+}
+
+// secretRule is a single built-in or user-supplied detection rule.
+type secretRule struct {
+	id      string
+	pattern *regexp.Regexp
+}
+
+// builtinSecretRules is the default ruleset used when no --secrets-config
+// is given, or merged with the rules a config file adds.
+var builtinSecretRules = []secretRule{
+	{"aws-access-key-id", regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`)},
+	{"aws-secret-access-key", regexp.MustCompile(`(?i)aws_secret_access_key\s*[:=]\s*['"]?([A-Za-z0-9/+=]{40})['"]?`)},
+	{"gcp-service-account-key", regexp.MustCompile(`"private_key_id":\s*"[0-9a-f]{40}"`)},
+	{"azure-storage-key", regexp.MustCompile(`(?i)AccountKey=[A-Za-z0-9+/=]{88}`)},
+	{"private-key", regexp.MustCompile(`-----BEGIN (RSA|DSA|EC|OPENSSH|PGP) PRIVATE KEY-----`)},
+	{"stripe-key", regexp.MustCompile(`\b(sk|rk)_(live|test)_[0-9a-zA-Z]{24,}\b`)},
+	{"jwt", regexp.MustCompile(`\bey[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\b`)},
+	{"generic-high-entropy-string", regexp.MustCompile(`[A-Za-z0-9+/=_-]{20,}`)},
+}
+
+// SecretsConfig is a user-supplied TOML or YAML file that can add custom
+// rules and allowlist patterns on top of the built-in ruleset.
+type SecretsConfig struct {
+	Rules []struct {
+		ID      string `toml:"id" yaml:"id"`
+		Pattern string `toml:"pattern" yaml:"pattern"`
+	} `toml:"rules" yaml:"rules"`
+	Allowlist struct {
+		Paths   []string `toml:"paths" yaml:"paths"`
+		Matches []string `toml:"matches" yaml:"matches"`
+		Commits []string `toml:"stopwords" yaml:"stopwords"`
+	} `toml:"allowlist" yaml:"allowlist"`
+}
+
+// LoadSecretsConfig reads a --secrets-config file, detecting TOML vs YAML
+// from its extension (.toml, or .yml/.yaml).
+func LoadSecretsConfig(path string) (*SecretsConfig, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &SecretsConfig{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		if err := toml.Unmarshal(contents, cfg); err != nil {
+			return nil, fmt.Errorf("unable to parse %s as TOML: %w", path, err)
+		}
+	case ".yml", ".yaml":
+		if err := yaml.Unmarshal(contents, cfg); err != nil {
+			return nil, fmt.Errorf("unable to parse %s as YAML: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("unrecognized secrets config extension for %s (expected .toml, .yml, or .yaml)", path)
+	}
+
+	return cfg, nil
+}
+
+// allowlist compiles a SecretsConfig's allowlist patterns into something
+// ScanForSecrets can cheaply test candidates against.
+type allowlist struct {
+	paths   []*regexp.Regexp
+	matches []*regexp.Regexp
+	commits []string
+}
+
+func (a *allowlist) allowsPath(path string) bool {
+	for _, re := range a.paths {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *allowlist) allowsMatch(match string) bool {
+	for _, re := range a.matches {
+		if re.MatchString(match) {
+			return true
+		}
+	}
+	for _, stopword := range a.commits {
+		if strings.Contains(match, stopword) {
+			return true
+		}
+	}
+	return false
+}
+
+func buildAllowlist(cfg *SecretsConfig) (*allowlist, error) {
+	a := &allowlist{}
+	if cfg == nil {
+		return a, nil
+	}
+	for _, p := range cfg.Allowlist.Paths {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowlist path pattern %q: %w", p, err)
+		}
+		a.paths = append(a.paths, re)
+	}
+	for _, m := range cfg.Allowlist.Matches {
+		re, err := regexp.Compile(m)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowlist match pattern %q: %w", m, err)
+		}
+		a.matches = append(a.matches, re)
+	}
+	a.commits = cfg.Allowlist.Commits
+	return a, nil
+}
+
+func buildRules(cfg *SecretsConfig) ([]secretRule, error) {
+	rules := make([]secretRule, len(builtinSecretRules))
+	copy(rules, builtinSecretRules)
+
+	if cfg == nil {
+		return rules, nil
+	}
+	for _, r := range cfg.Rules {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid custom rule %q: %w", r.ID, err)
+		}
+		rules = append(rules, secretRule{id: r.ID, pattern: re})
+	}
+	return rules, nil
+}
+
+// ScanForSecrets walks cookbookPath looking for hardcoded credentials,
+// using the built-in ruleset plus anything cfg adds. Matches whose path or
+// content are allowlisted by cfg are dropped.
+func ScanForSecrets(cookbookPath string, cfg *SecretsConfig) ([]SecretFinding, error) {
+	rules, err := buildRules(cfg)
+	if err != nil {
+		return nil, err
+	}
+	allow, err := buildAllowlist(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ignore, err := loadChefignore(cookbookPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []SecretFinding
+
+	err = filepath.Walk(cookbookPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || info.Size() > 1<<20 {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(cookbookPath, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if ignore.Excludes(relPath) || allow.allowsPath(relPath) {
+			return nil
+		}
+
+		fileFindings, err := scanFile(path, relPath, rules, allow)
+		if err != nil {
+			return nil // unreadable/binary files are skipped, not fatal
+		}
+		findings = append(findings, fileFindings...)
+		return nil
+	})
+
+	return findings, err
+}
+
+func scanFile(path, relPath string, rules []secretRule, allow *allowlist) ([]SecretFinding, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var findings []SecretFinding
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+
+		for _, rule := range rules {
+			match := rule.pattern.FindString(line)
+			if match == "" {
+				continue
+			}
+
+			if rule.id == "generic-high-entropy-string" {
+				if len(match) < minHighEntropyCandidateLength {
+					continue
+				}
+				entropy := shannonEntropy(match)
+				if entropy < MinSecretEntropy {
+					continue
+				}
+				if allow.allowsMatch(match) {
+					continue
+				}
+				findings = append(findings, SecretFinding{
+					RuleID: rule.id, Path: relPath, Line: lineNum,
+					Match: redact(match), Entropy: entropy,
+				})
+				continue
+			}
+
+			if allow.allowsMatch(match) {
+				continue
+			}
+			findings = append(findings, SecretFinding{
+				RuleID: rule.id, Path: relPath, Line: lineNum,
+				Match: redact(match), Entropy: shannonEntropy(match),
+			})
+		}
+	}
+
+	return findings, scanner.Err()
+}
+
+// redact keeps the first and last two characters of a match and replaces
+// the rest with asterisks, so findings can be shared (in CI output, bug
+// reports, etc) without leaking the credential itself.
+func redact(match string) string {
+	if len(match) <= 8 {
+		return strings.Repeat("*", len(match))
+	}
+	return match[:2] + strings.Repeat("*", len(match)-4) + match[len(match)-2:]
+}
+
+// shannonEntropy returns the Shannon entropy of s, in bits per character.
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+
+	var entropy float64
+	length := float64(len(s))
+	for _, count := range counts {
+		p := float64(count) / length
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
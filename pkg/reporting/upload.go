@@ -0,0 +1,419 @@
+//
+// Copyright 2019 Chef Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reporting
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cookbookSegments lists the standard top-level directories Chef Infra
+// Server groups cookbook files into. Anything that isn't inside one of
+// these is bundled under "root_files" (metadata.rb, README, chefignore
+// itself is never uploaded, etc).
+var cookbookSegments = []string{
+	"recipes", "attributes", "libraries", "files", "templates",
+	"resources", "providers", "definitions",
+}
+
+// ManifestFile is a single file tracked by an UploadManifest.
+type ManifestFile struct {
+	// Name is the path of the file relative to the cookbook root, using
+	// forward slashes, e.g. "recipes/default.rb".
+	Name string
+	// Checksum is the hex encoded SHA-256 digest of the file contents.
+	Checksum string
+}
+
+// UploadManifest describes a cookbook version ready to be uploaded to a
+// Chef Infra Server, grouped into the segments the server API expects.
+type UploadManifest struct {
+	CookbookName string
+	Version      string
+	RootDir      string
+	Segments     map[string][]ManifestFile
+	// Metadata is the cookbook's metadata.rb/metadata.json rendered into
+	// the "metadata" sub-document the server's cookbook_version schema
+	// requires (maintainer, description, dependencies, etc).
+	Metadata map[string]interface{}
+}
+
+// UploadOptions controls how a cookbook is uploaded to the Chef Infra
+// Server.
+type UploadOptions struct {
+	// Freeze marks the uploaded cookbook version as frozen, preventing it
+	// from being overwritten by a future upload of the same version.
+	Freeze bool
+}
+
+// buildUploadManifest walks cookbookPath, honoring any chefignore file at
+// its root, computes a SHA-256 digest for every remaining file, and groups
+// them into the segments the Chef Infra Server API expects.
+func buildUploadManifest(name, version, cookbookPath string) (*UploadManifest, error) {
+	ignore, err := loadChefignore(cookbookPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read chefignore: %w", err)
+	}
+
+	metadata, err := buildMetadataDocument(cookbookPath, name, version)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read cookbook metadata: %w", err)
+	}
+
+	manifest := &UploadManifest{
+		CookbookName: name,
+		Version:      version,
+		RootDir:      cookbookPath,
+		Segments:     make(map[string][]ManifestFile),
+		Metadata:     metadata,
+	}
+
+	err = filepath.Walk(cookbookPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(cookbookPath, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		if relPath == "chefignore" || ignore.Excludes(relPath) {
+			return nil
+		}
+
+		checksum, err := sha256File(path)
+		if err != nil {
+			return err
+		}
+
+		segment := segmentFor(relPath)
+		manifest.Segments[segment] = append(manifest.Segments[segment], ManifestFile{
+			Name:     relPath,
+			Checksum: checksum,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// segmentFor returns the manifest segment a cookbook-relative path belongs
+// to, defaulting to "root_files" for anything outside the standard
+// directories.
+func segmentFor(relPath string) string {
+	for _, segment := range cookbookSegments {
+		if strings.HasPrefix(relPath, segment+"/") {
+			return segment
+		}
+	}
+	return "root_files"
+}
+
+// sha256File returns the hex encoded SHA-256 digest of a file's contents.
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// uploadCookbook uploads manifest to the Chef Infra Server pointed at by
+// client, negotiating between the V0 (per-file, /cookbooks/<name>/<version>)
+// and V2 (/cookbook_artifacts/...) APIs via the X-Ops-Server-API-Version
+// header, as described in https://github.com/chef/chef-rfc/blob/main/rfc099-api-versioning.md.
+func uploadCookbook(client *ChefClient, manifest *UploadManifest, opts UploadOptions) error {
+	apiVersion, err := negotiateServerAPIVersion(client)
+	if err != nil {
+		return fmt.Errorf("unable to negotiate Chef Infra Server API version: %w", err)
+	}
+
+	if err := uploadMissingChecksums(client, manifest); err != nil {
+		return fmt.Errorf("unable to upload sandbox checksums: %w", err)
+	}
+
+	if apiVersion >= 2 {
+		return uploadCookbookV2(client, manifest, opts)
+	}
+	return uploadCookbookV0(client, manifest, opts)
+}
+
+// negotiateServerAPIVersion asks the Chef Infra Server which API versions
+// it supports by reading the X-Ops-Server-API-Version header returned on
+// any authenticated request, and returns the highest version both the
+// client and server agree on. Paths passed to the client are relative to
+// the organization base URL the client was configured with, so no
+// "/organizations/<org>" prefix is needed here.
+func negotiateServerAPIVersion(client *ChefClient) (int, error) {
+	resp, err := client.Get("cookbooks")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	header := resp.Header.Get("X-Ops-Server-API-Version")
+	if header == "" {
+		// Chef Infra Server versions that predate API versioning only
+		// support the V0 cookbook endpoints.
+		return 0, nil
+	}
+
+	var negotiation struct {
+		MinAPIVersion int `json:"min_version,string"`
+		MaxAPIVersion int `json:"max_version,string"`
+	}
+	if err := json.Unmarshal([]byte(header), &negotiation); err != nil {
+		return 0, fmt.Errorf("unable to parse X-Ops-Server-API-Version header %q: %w", header, err)
+	}
+
+	if negotiation.MaxAPIVersion >= 2 {
+		return 2, nil
+	}
+	return negotiation.MaxAPIVersion, nil
+}
+
+// uploadMissingChecksums asks the Chef Infra Server's sandbox endpoint
+// which of the manifest's file checksums it doesn't already have, PUTs the
+// contents for each, and commits the sandbox.
+func uploadMissingChecksums(client *ChefClient, manifest *UploadManifest) error {
+	checksums := make(map[string]bool)
+	for _, files := range manifest.Segments {
+		for _, f := range files {
+			checksums[f.Checksum] = true
+		}
+	}
+
+	sandbox, err := createSandbox(client, checksums)
+	if err != nil {
+		return err
+	}
+
+	for checksum, upload := range sandbox.Checksums {
+		if !upload.NeedsUpload {
+			continue
+		}
+		path := manifest.pathForChecksum(checksum)
+		if path == "" {
+			return fmt.Errorf("sandbox requested checksum %s which is not part of the manifest", checksum)
+		}
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := client.PutRaw(upload.URL, contents); err != nil {
+			return fmt.Errorf("unable to upload %s to sandbox: %w", path, err)
+		}
+	}
+
+	return commitSandbox(client, sandbox.SandboxID)
+}
+
+type sandboxChecksum struct {
+	NeedsUpload bool   `json:"needs_upload"`
+	URL         string `json:"url"`
+}
+
+type sandboxResponse struct {
+	SandboxID string                     `json:"sandbox_id"`
+	Checksums map[string]sandboxChecksum `json:"checksums"`
+}
+
+func createSandbox(client *ChefClient, checksums map[string]bool) (*sandboxResponse, error) {
+	body := struct {
+		Checksums map[string]bool `json:"checksums"`
+	}{Checksums: checksums}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.PostJSON("sandboxes", payload)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var sandbox sandboxResponse
+	if err := json.NewDecoder(resp.Body).Decode(&sandbox); err != nil {
+		return nil, err
+	}
+	return &sandbox, nil
+}
+
+func commitSandbox(client *ChefClient, sandboxID string) error {
+	payload, err := json.Marshal(struct {
+		IsCompleted bool `json:"is_completed"`
+	}{IsCompleted: true})
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.PutJSON("sandboxes/"+sandboxID, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("sandbox commit failed with status %s", resp.Status)
+	}
+	return nil
+}
+
+// pathForChecksum returns the local, on-disk path for a file tracked
+// under checksum, or "" if the manifest doesn't contain it.
+func (m *UploadManifest) pathForChecksum(checksum string) string {
+	for _, files := range m.Segments {
+		for _, f := range files {
+			if f.Checksum == checksum {
+				return filepath.Join(m.RootDir, filepath.FromSlash(f.Name))
+			}
+		}
+	}
+	return ""
+}
+
+// uploadCookbookV0 uploads manifest using the legacy per-cookbook-version
+// endpoint, PUTing the full cookbook document to
+// /cookbooks/<name>/<version>.
+func uploadCookbookV0(client *ChefClient, manifest *UploadManifest, opts UploadOptions) error {
+	doc := manifest.toServerDocument(opts)
+	payload, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("cookbooks/%s/%s", manifest.CookbookName, manifest.Version)
+	resp, err := client.PutJSON(path, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("upload failed with status %s", resp.Status)
+	}
+	return nil
+}
+
+// uploadCookbookV2 uploads manifest using the cookbook_artifacts endpoint
+// introduced with Chef Infra Server API V2, identifying the artifact by
+// its content identifier rather than its semantic version.
+func uploadCookbookV2(client *ChefClient, manifest *UploadManifest, opts UploadOptions) error {
+	identifier := manifest.contentIdentifier()
+	doc := manifest.toServerDocument(opts)
+	payload, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("cookbook_artifacts/%s/%s", manifest.CookbookName, identifier)
+	resp, err := client.PutJSON(path, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("upload failed with status %s", resp.Status)
+	}
+	return nil
+}
+
+// contentIdentifier derives the content-addressed identifier the V2 API
+// uses in place of a semantic version, by hashing every file checksum in
+// the manifest together, including root_files (e.g. metadata.rb).
+func (m *UploadManifest) contentIdentifier() string {
+	var buf bytes.Buffer
+	for _, segment := range cookbookSegments {
+		for _, f := range m.Segments[segment] {
+			buf.WriteString(f.Name)
+			buf.WriteString(f.Checksum)
+		}
+	}
+	for _, f := range m.Segments["root_files"] {
+		buf.WriteString(f.Name)
+		buf.WriteString(f.Checksum)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:])
+}
+
+// toServerDocument renders the manifest into the cookbook_version JSON
+// document the Chef Infra Server cookbook upload endpoints expect:
+// identifying fields ("name", "cookbook_name", "json_class", "chef_type"),
+// the cookbook's full "metadata" (maintainer, description, dependencies,
+// etc, read from metadata.rb/metadata.json), and one array of
+// {name, path, checksum} entries per segment.
+func (m *UploadManifest) toServerDocument(opts UploadOptions) map[string]interface{} {
+	doc := map[string]interface{}{
+		"name":          fmt.Sprintf("%s-%s", m.CookbookName, m.Version),
+		"cookbook_name": m.CookbookName,
+		"version":       m.Version,
+		"json_class":    "Chef::CookbookVersion",
+		"chef_type":     "cookbook_version",
+		"frozen?":       opts.Freeze,
+		"metadata":      m.Metadata,
+	}
+	for _, segment := range cookbookSegments {
+		files := m.Segments[segment]
+		entries := make([]map[string]string, len(files))
+		for i, f := range files {
+			entries[i] = map[string]string{
+				"name":     filepath.Base(f.Name),
+				"path":     f.Name,
+				"checksum": f.Checksum,
+			}
+		}
+		doc[segment] = entries
+	}
+	if rootFiles := m.Segments["root_files"]; len(rootFiles) > 0 {
+		entries := make([]map[string]string, len(rootFiles))
+		for i, f := range rootFiles {
+			entries[i] = map[string]string{
+				"name":     filepath.Base(f.Name),
+				"path":     f.Name,
+				"checksum": f.Checksum,
+			}
+		}
+		doc["root_files"] = entries
+	}
+	return doc
+}
@@ -0,0 +1,149 @@
+//
+// Copyright 2019 Chef Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package reporting
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestCookbook(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "chef-analyze-upload-test-")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	files := map[string]string{
+		"metadata.rb":           "name 'example'\nversion '1.0.0'\nmaintainer 'Chef Software'\ndepends 'apt'\n",
+		"recipes/default.rb":    "# no-op\n",
+		"attributes/default.rb": "default['example'] = true\n",
+		"chefignore":            "spec/*\n",
+		"spec/default_spec.rb":  "# should be ignored\n",
+	}
+	for relPath, contents := range files {
+		path := filepath.Join(dir, relPath)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("unable to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatalf("unable to write %s: %v", path, err)
+		}
+	}
+
+	return dir
+}
+
+func TestBuildUploadManifest(t *testing.T) {
+	dir := writeTestCookbook(t)
+
+	manifest, err := buildUploadManifest("example", "1.0.0", dir)
+	if err != nil {
+		t.Fatalf("buildUploadManifest returned an error: %v", err)
+	}
+
+	if len(manifest.Segments["recipes"]) != 1 {
+		t.Errorf("expected 1 recipe file, got %d", len(manifest.Segments["recipes"]))
+	}
+	if len(manifest.Segments["attributes"]) != 1 {
+		t.Errorf("expected 1 attributes file, got %d", len(manifest.Segments["attributes"]))
+	}
+	if len(manifest.Segments["root_files"]) != 1 {
+		t.Errorf("expected 1 root file (metadata.rb), got %d", len(manifest.Segments["root_files"]))
+	}
+	for _, f := range manifest.Segments["spec"] {
+		t.Errorf("expected spec/ files to be excluded by chefignore, found %s", f.Name)
+	}
+
+	if manifest.Metadata["maintainer"] != "Chef Software" {
+		t.Errorf("expected maintainer to be read from metadata.rb, got %v", manifest.Metadata["maintainer"])
+	}
+}
+
+func TestUploadManifestToServerDocument(t *testing.T) {
+	dir := writeTestCookbook(t)
+
+	manifest, err := buildUploadManifest("example", "1.0.0", dir)
+	if err != nil {
+		t.Fatalf("buildUploadManifest returned an error: %v", err)
+	}
+
+	doc := manifest.toServerDocument(UploadOptions{Freeze: true})
+
+	if doc["name"] != "example-1.0.0" {
+		t.Errorf(`expected "name" to be "example-1.0.0", got %v`, doc["name"])
+	}
+	if doc["cookbook_name"] != "example" {
+		t.Errorf(`expected "cookbook_name" to be "example", got %v`, doc["cookbook_name"])
+	}
+	if doc["json_class"] != "Chef::CookbookVersion" {
+		t.Errorf(`expected "json_class" to be "Chef::CookbookVersion", got %v`, doc["json_class"])
+	}
+	if doc["chef_type"] != "cookbook_version" {
+		t.Errorf(`expected "chef_type" to be "cookbook_version", got %v`, doc["chef_type"])
+	}
+	if doc["frozen?"] != true {
+		t.Errorf(`expected "frozen?" to be true, got %v`, doc["frozen?"])
+	}
+
+	metadata, ok := doc["metadata"].(map[string]interface{})
+	if !ok {
+		t.Fatalf(`expected "metadata" to be a map, got %T`, doc["metadata"])
+	}
+	if metadata["maintainer"] != "Chef Software" {
+		t.Errorf(`expected metadata["maintainer"] to be "Chef Software", got %v`, metadata["maintainer"])
+	}
+}
+
+func TestSegmentFor(t *testing.T) {
+	cases := map[string]string{
+		"recipes/default.rb":      "recipes",
+		"attributes/default.rb":   "attributes",
+		"templates/default/x.erb": "templates",
+		"metadata.rb":             "root_files",
+		"README.md":               "root_files",
+	}
+	for relPath, want := range cases {
+		if got := segmentFor(relPath); got != want {
+			t.Errorf("segmentFor(%q) = %q, want %q", relPath, got, want)
+		}
+	}
+}
+
+func TestContentIdentifierIncludesRootFiles(t *testing.T) {
+	manifest := &UploadManifest{
+		CookbookName: "example",
+		Version:      "1.0.0",
+		Segments: map[string][]ManifestFile{
+			"recipes":    {{Name: "recipes/default.rb", Checksum: "aaa"}},
+			"root_files": {{Name: "metadata.rb", Checksum: "bbb"}},
+		},
+	}
+
+	before := manifest.contentIdentifier()
+
+	manifest.Segments["root_files"] = []ManifestFile{{Name: "metadata.rb", Checksum: "ccc"}}
+	after := manifest.contentIdentifier()
+
+	if before == after {
+		t.Errorf("expected contentIdentifier to change when root_files checksum changes, got the same value %q for both", before)
+	}
+}